@@ -0,0 +1,301 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+const quickFix = "quickfix"
+
+const indentUnit = "    "
+
+// Identifiers a missing element can be created for, hierarchical identifiers are left alone.
+var plainIdentifier = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Offers quick fixes for the diagnostics of the document sent by the client and the refactorings of the range.
+func (l *Lsp) handleCodeAction(id int, params CodeActionParams) {
+	actions := make([]CodeAction, 0)
+	content, ok := l.content[params.TextDocument.URI]
+	if !ok || content.Ast == nil {
+		l.sendResponse(id, actions)
+		return
+	}
+	fixer := &quickFixer{
+		l:     l,
+		path:  pathFromURI(params.TextDocument.URI),
+		lines: strings.Split(content.Text, "\n"),
+		ast:   content.Ast,
+	}
+	for _, diag := range params.Context.Diagnostics {
+		if action := fixer.fix(diag); action != nil {
+			action.Kind = quickFix
+			action.Diagnostics = []Diagnostic{diag}
+			actions = append(actions, *action)
+		}
+	}
+	if action := l.extractIncludeAction(params.TextDocument.URI, params.Range); action != nil {
+		actions = append(actions, *action)
+	}
+	actions = append(actions, l.inlineIncludeActions(params.TextDocument.URI, params.Range)...)
+	if action := l.convertIdentifiersAction(params.TextDocument.URI, params.Range); action != nil {
+		actions = append(actions, *action)
+	}
+	if len(params.Context.Only) > 0 {
+		actions = slices.DeleteFunc(actions, func(a CodeAction) bool { return !requested(a.Kind, params.Context.Only) })
+	}
+	l.sendResponse(id, actions)
+}
+
+type quickFixer struct {
+	l     *Lsp
+	path  string
+	lines []string
+	ast   *parser.ASTNode
+}
+
+// Returns the fix of the diagnostic, nil when there is none or the document changed since it was reported.
+func (f *quickFixer) fix(diag Diagnostic) *CodeAction {
+	code, data, ok := ownDiagnostic(diag)
+	if !ok {
+		return nil
+	}
+	loc := f.l.fromPosition(f.path, diag.Range.Start)
+	switch code {
+	case parser.CodeMissingModel:
+		return f.addBlock(loc, "model")
+	case parser.CodeMissingViews:
+		return f.addBlock(loc, "views")
+	case parser.CodeOpeningBrace:
+		return f.joinOpeningBrace(loc)
+	case parser.CodeClosingBrace:
+		return f.splitClosingBrace(loc)
+	case parser.CodeInvalidOption:
+		return f.replaceOption(loc, data)
+	case parser.CodeUnknownIdentifier:
+		if len(data) == 1 {
+			return f.createElement(loc, data[0])
+		}
+	}
+	return nil
+}
+
+// Adds the model after the opening brace of the workspace, the views before its closing brace.
+func (f *quickFixer) addBlock(loc parser.Location, keyword string) *CodeAction {
+	workspace := nodeStartingAt(f.ast, loc, parser.NodeWorkspace)
+	if workspace == nil {
+		return nil
+	}
+	open, close := blockOf(workspace)
+	if open == nil || close == nil || open.Location.Line == close.Location.Line {
+		return nil
+	}
+	indent := f.indentation(workspace.Location.Line) + indentUnit
+	at := parser.Location{Source: f.path, Line: open.Location.Line + 1}
+	if keyword == "views" {
+		if strings.TrimSpace(f.before(close.Location)) != "" {
+			return nil
+		}
+		at.Line = close.Location.Line
+	}
+	text := fmt.Sprintf("%s%s {\n%s}\n", indent, keyword, indent)
+	return f.action(fmt.Sprintf("Add %s block", keyword), parser.Range{Start: at, End: at}, text)
+}
+
+// Moves the opening brace to the end of the statement on the previous line.
+func (f *quickFixer) joinOpeningBrace(loc parser.Location) *CodeAction {
+	brace := nodeStartingAt(f.ast, loc, parser.NodeBlockStart)
+	if brace == nil || brace.Parent == nil {
+		return nil
+	}
+	owner := brace.Parent
+	end := owner.End
+	for _, a := range owner.Attributes {
+		if a.Location.Source == f.path && end.Before(a.End) {
+			end = a.End
+		}
+	}
+	// comments between the statement and the brace would be lost
+	if strings.TrimSpace(f.between(end, brace.Location)) != "" {
+		return nil
+	}
+	return f.action("Move { to the end of the previous line", parser.Range{Start: end, End: brace.End}, " {")
+}
+
+// Moves the closing brace to a line of its own, indented like the statement of its block.
+func (f *quickFixer) splitClosingBrace(loc parser.Location) *CodeAction {
+	brace := nodeStartingAt(f.ast, loc, parser.NodeBlockEnd)
+	if brace == nil || brace.Parent == nil {
+		return nil
+	}
+	before := strings.TrimRight(f.before(brace.Location), " \t")
+	if before == "" {
+		return nil
+	}
+	start := parser.Location{Source: f.path, Line: loc.Line, Pos: len([]rune(before))}
+	return f.action("Move } to a line of its own", parser.Range{Start: start, End: brace.Location}, "\n"+f.indentation(brace.Parent.Location.Line))
+}
+
+// Replaces the option with the closest possible value.
+func (f *quickFixer) replaceOption(loc parser.Location, values []string) *CodeAction {
+	node := nodeStartingAt(f.ast, loc, "")
+	if node == nil || len(node.Attributes) == 0 || len(values) == 0 {
+		return nil
+	}
+	option := node.Attributes[0]
+	closest := values[0]
+	for _, v := range values[1:] {
+		if distance(strings.ToLower(option.Content), v) < distance(strings.ToLower(option.Content), closest) {
+			closest = v
+		}
+	}
+	return f.action("Replace with "+closest, parser.Range{Start: option.Location, End: option.End}, closest)
+}
+
+// Defines the missing element as a software system, right before the statement of the model using it or at the
+// end of the model when it is used outside of the model.
+func (f *quickFixer) createElement(loc parser.Location, identifier string) *CodeAction {
+	if !plainIdentifier.MatchString(identifier) {
+		return nil
+	}
+	statement := nodeAt(f.ast, loc)
+	for statement != nil && (statement.Parent == nil || statement.Parent.Kind != parser.NodeModel) {
+		statement = statement.Parent
+	}
+	text := fmt.Sprintf("%s = softwareSystem \"%s\"\n", identifier, identifier)
+	title := "Create software system " + identifier
+	if statement != nil {
+		at := parser.Location{Source: f.path, Line: statement.Range.Start.Line}
+		return f.action(title, parser.Range{Start: at, End: at}, f.indentation(at.Line)+text)
+	}
+	model := modelOf(f.ast)
+	if model == nil || model.Location.Source != f.path {
+		return nil
+	}
+	_, close := blockOf(model)
+	if close == nil || close.Location.Source != f.path || strings.TrimSpace(f.before(close.Location)) != "" {
+		return nil
+	}
+	at := parser.Location{Source: f.path, Line: close.Location.Line}
+	return f.action(title, parser.Range{Start: at, End: at}, f.indentation(model.Location.Line)+indentUnit+text)
+}
+
+// Kinds are hierarchical, refactor covers refactor.extract too.
+func requested(kind string, only []string) bool {
+	return slices.ContainsFunc(only, func(o string) bool { return kind == o || strings.HasPrefix(kind, o+".") })
+}
+
+func (f *quickFixer) action(title string, rng parser.Range, text string) *CodeAction {
+	uri := uriFromPath(f.path)
+	edit := TextEdit{Range: f.l.toLocation(rng).Range, NewText: text}
+	return &CodeAction{Title: title, Edit: &WorkspaceEdit{Changes: map[string][]TextEdit{uri: {edit}}}}
+}
+
+// Returns the leading whitespace of the line.
+func (f *quickFixer) indentation(line int) string {
+	if line < 0 || line >= len(f.lines) {
+		return ""
+	}
+	text := f.lines[line]
+	return text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+}
+
+// Returns the text of the line before the location.
+func (f *quickFixer) before(loc parser.Location) string {
+	return f.between(parser.Location{Source: loc.Source, Line: loc.Line}, loc)
+}
+
+// Returns the text between the locations, lines are joined by newlines.
+func (f *quickFixer) between(start parser.Location, end parser.Location) string {
+	var sb strings.Builder
+	for line := start.Line; line <= end.Line && line < len(f.lines); line++ {
+		runes := []rune(f.lines[line])
+		from, to := 0, len(runes)
+		if line == start.Line {
+			from = min(start.Pos, len(runes))
+		}
+		if line == end.Line {
+			to = min(end.Pos, len(runes))
+		}
+		if line > start.Line {
+			sb.WriteString("\n")
+		}
+		if from < to {
+			sb.WriteString(string(runes[from:to]))
+		}
+	}
+	return sb.String()
+}
+
+// Returns the node of the given kind starting at the location, any kind matches when the kind is empty.
+func nodeStartingAt(node *parser.ASTNode, loc parser.Location, kind parser.NodeKind) *parser.ASTNode {
+	if node.Location == loc && (kind == "" || node.Kind == kind) && node.Kind != parser.NodeRoot {
+		return node
+	}
+	for _, c := range node.Children {
+		if found := nodeStartingAt(c, loc, kind); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Returns the opening and closing braces of the block of the node.
+func blockOf(node *parser.ASTNode) (*parser.ASTNode, *parser.ASTNode) {
+	var open, close *parser.ASTNode
+	for _, c := range node.Children {
+		if c.Kind == parser.NodeBlockStart && open == nil {
+			open = c
+		} else if c.Kind == parser.NodeBlockEnd {
+			close = c
+		}
+	}
+	return open, close
+}
+
+// Returns the workspace of the document, nil for files included by a workspace.
+func workspaceOf(root *parser.ASTNode) *parser.ASTNode {
+	for _, w := range root.Children {
+		if w.Kind == parser.NodeWorkspace {
+			return w
+		}
+	}
+	return nil
+}
+
+// Returns the model of the workspace.
+func modelOf(root *parser.ASTNode) *parser.ASTNode {
+	if w := workspaceOf(root); w != nil {
+		for _, c := range w.Children {
+			if c.Kind == parser.NodeModel {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the Levenshtein distance of the strings.
+func distance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(rb)]
+}
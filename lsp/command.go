@@ -0,0 +1,73 @@
+package lsp
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/tacsiazuma/structurizr-lsp/rpc"
+)
+
+// Commands the client may execute, mostly refactorings offered as code actions.
+var commands = []string{extractIncludeCommand, inlineIncludeCommand, convertIdentifiersCommand}
+
+// Executes the command and asks the client to apply its edit, the command fails when the client does not apply it.
+func (l *Lsp) handleExecuteCommand(id int, params ExecuteCommandParams) {
+	var args RefactoringArguments
+	if len(params.Arguments) != 1 || json.Unmarshal(params.Arguments[0], &args) != nil {
+		l.sendError(id, -32602, "Expected the document and the range as arguments of "+params.Command)
+		return
+	}
+	var edit *WorkspaceEdit
+	var err error
+	var label string
+	switch params.Command {
+	case extractIncludeCommand:
+		label = "Extract to !include file"
+		edit, err = l.extractInclude(args)
+	case inlineIncludeCommand:
+		label = "Inline !include"
+		edit, err = l.inlineInclude(args)
+	case convertIdentifiersCommand:
+		label = "Convert identifiers"
+		edit, err = l.convertIdentifiers(args)
+	default:
+		l.sendError(id, -32602, "Unknown command "+params.Command)
+		return
+	}
+	if err != nil {
+		l.sendError(id, -32602, err.Error())
+		return
+	}
+	l.sendRequest("workspace/applyEdit", ApplyWorkspaceEditParams{Label: label, Edit: *edit}, func(result json.RawMessage, err *rpc.Error) {
+		var applied ApplyWorkspaceEditResult
+		reason := ""
+		if err != nil {
+			reason = err.Message
+		} else if json.Unmarshal(result, &applied) != nil || !applied.Applied {
+			reason = cmp.Or(applied.FailureReason, "the client did not apply the edit")
+		}
+		if reason != "" {
+			l.logger.Printf("Failed to apply '%s': %s", label, reason)
+			// RequestFailed
+			l.sendError(id, -32803, fmt.Sprintf("Failed to apply '%s': %s", label, reason))
+			return
+		}
+		l.sendResponse(id, nil)
+	})
+}
+
+// Reports whether the client applies document changes with the given file operations, like creating or deleting
+// files.
+func (l *Lsp) supportsDocumentChanges(operations ...string) bool {
+	if !l.workspaceEdit.DocumentChanges {
+		return false
+	}
+	for _, op := range operations {
+		if !slices.Contains(l.workspaceEdit.ResourceOperations, op) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+// Keywords of the element types, archetypes are offered besides them.
+var elementKeywords = []string{
+	"person", "softwareSystem", "container", "component", "group", "enterprise", "element", "deploymentEnvironment",
+	"deploymentGroup", "deploymentNode", "infrastructureNode", "softwareSystemInstance", "containerInstance",
+}
+
+// Offers the element keywords, the archetypes and the identifiers of the workspace containing the document.
+func (l *Lsp) handleCompletion(id int, params TextDocumentPositionParams) {
+	items := make([]CompletionItem, 0)
+	for _, k := range elementKeywords {
+		items = append(items, CompletionItem{Label: k, Kind: KeywordCompletion})
+	}
+	if workspaces := l.workspacesOf(pathFromURI(params.TextDocument.URI)); len(workspaces) > 0 {
+		items = append(items, modelCompletions(workspaces[0].Model)...)
+	}
+	l.sendResponse(id, items)
+}
+
+func modelCompletions(model *parser.Model) []CompletionItem {
+	items := make([]CompletionItem, 0)
+	archetypes := make([]CompletionItem, 0)
+	for _, a := range model.Archetypes {
+		if a.Type != "->" {
+			archetypes = append(archetypes, CompletionItem{Label: a.Name, Kind: ClassCompletion, Detail: "archetype of " + a.Type})
+		}
+	}
+	// archetypes come from a map, keep the items stable between requests
+	slices.SortFunc(archetypes, func(a, b CompletionItem) int { return strings.Compare(a.Label, b.Label) })
+	items = append(items, archetypes...)
+	for _, e := range model.Elements {
+		if e.Identifier != "" {
+			items = append(items, CompletionItem{Label: e.Identifier, Kind: VariableCompletion, Detail: e.Type + " " + e.Name})
+		}
+	}
+	return items
+}
+
+// Items are complete already, resolving returns them as they are.
+func (l *Lsp) handleCompletionResolve(id int, item json.RawMessage) {
+	l.sendResponse(id, item)
+}
@@ -2,13 +2,12 @@ package lsp
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
-
-	"github.com/tacsiazuma/structurizr-lsp/parser"
 )
 
-func (l *Lsp) registerContent(uri, content string, ast *parser.ASTNode) {
-	l.content[uri] = Content{Text: content, Ast: ast}
+func (l *Lsp) registerContent(uri, content string, a *analysis) {
+	l.content[uri] = Content{Text: content, Lines: strings.Split(content, "\n"), Ast: a.ast, Workspace: a.workspace, Graph: a.graph}
 	l.logger.Println("Writing " + uri)
 }
 
@@ -23,11 +22,35 @@ func (l *Lsp) getContent(uri string) (*Content, error) {
 
 func (l *Lsp) getOrUpdateContent(uri, text string) (*Content, error) {
 	if text != "" {
-		p := parser.NewAnalyser(strings.TrimPrefix(uri, "file://"), text)
-		_, ast, _ := p.Analyse()
-		l.registerContent(uri, text, ast)
+		l.registerContent(uri, text, l.analyse(pathFromURI(uri), text))
 	}
 	content, err := l.getContent(uri)
 	return content, err
 }
 
+func pathFromURI(uri string) string {
+	if isRemote(uri) {
+		return uri
+	}
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return strings.TrimPrefix(uri, "file://")
+	}
+	return u.Path
+}
+
+func uriFromPath(path string) string {
+	if isRemote(path) {
+		return path
+	}
+	uri := &url.URL{
+		Scheme: "file",
+		Path:   path,
+	}
+	return uri.String()
+}
+
+// Sources of remote includes are already URIs.
+func isRemote(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
@@ -1,8 +1,15 @@
 package lsp
 
+import "encoding/json"
+
 type Diagnostic struct {
 	Range   Range  `json:"range"`
 	Message string `json:"message"`
+	Source  string `json:"source,omitempty"`
+	// A string for our diagnostics, other sources may use integers
+	Code json.RawMessage `json:"code,omitempty"`
+	// Sent back by the client with the code actions, details of the problem depending on its code
+	Data json.RawMessage `json:"data,omitempty"`
 }
 
 type Range struct {
@@ -44,6 +51,14 @@ type DidOpenTextDocumentParams struct {
 	TextDocument TextDocumentItem `json:"textDocument"`
 }
 
+type DidSaveTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
 type InlayHintParams struct {
 	TextDocument TextDocumentItem `json:"textDocument"`
 	Range        Range            `json:"range"`
@@ -74,3 +89,305 @@ type TextEdit struct {
 	Range   Range  `json:"range"`
 	NewText string `json:"newText"`
 }
+
+type InitializeParams struct {
+	Capabilities          ClientCapabilities    `json:"capabilities"`
+	InitializationOptions InitializationOptions `json:"initializationOptions"`
+	RootURI               string                `json:"rootUri"`
+	WorkspaceFolders      []WorkspaceFolder     `json:"workspaceFolders"`
+}
+
+type WorkspaceFolder struct {
+	URI  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+type InitializationOptions struct {
+	// Resolve remote includes from the cache only
+	Offline bool `json:"offline"`
+	// Timeout of fetching remote includes in milliseconds
+	IncludeTimeout int `json:"includeTimeout"`
+}
+
+type ClientCapabilities struct {
+	General   GeneralClientCapabilities   `json:"general"`
+	Workspace WorkspaceClientCapabilities `json:"workspace"`
+	Window    WindowClientCapabilities    `json:"window"`
+}
+
+type WindowClientCapabilities struct {
+	WorkDoneProgress bool `json:"workDoneProgress"`
+}
+
+type WorkspaceClientCapabilities struct {
+	DidChangeWatchedFiles DynamicRegistrationCapability   `json:"didChangeWatchedFiles"`
+	WorkspaceEdit         WorkspaceEditClientCapabilities `json:"workspaceEdit"`
+}
+
+type WorkspaceEditClientCapabilities struct {
+	DocumentChanges bool `json:"documentChanges"`
+	// File operations the client applies, like create and delete
+	ResourceOperations []string `json:"resourceOperations"`
+}
+
+type DynamicRegistrationCapability struct {
+	DynamicRegistration bool `json:"dynamicRegistration"`
+}
+
+type GeneralClientCapabilities struct {
+	PositionEncodings []PositionEncodingKind `json:"positionEncodings"`
+}
+
+type RegistrationParams struct {
+	Registrations []Registration `json:"registrations"`
+}
+
+type Registration struct {
+	ID              string      `json:"id"`
+	Method          string      `json:"method"`
+	RegisterOptions interface{} `json:"registerOptions,omitempty"`
+}
+
+type UnregistrationParams struct {
+	// the misspelling is part of the protocol
+	Unregisterations []Unregistration `json:"unregisterations"`
+}
+
+type Unregistration struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+}
+
+type DidChangeWatchedFilesRegistrationOptions struct {
+	Watchers []FileSystemWatcher `json:"watchers"`
+}
+
+type FileSystemWatcher struct {
+	GlobPattern string `json:"globPattern"`
+}
+
+type FileChangeType int
+
+var (
+	FileCreated FileChangeType = 1
+	FileChanged FileChangeType = 2
+	FileDeleted FileChangeType = 3
+)
+
+type DidChangeWatchedFilesParams struct {
+	Changes []FileEvent `json:"changes"`
+}
+
+type FileEvent struct {
+	URI  string         `json:"uri"`
+	Type FileChangeType `json:"type"`
+}
+
+type DidChangeWorkspaceFoldersParams struct {
+	Event WorkspaceFoldersChangeEvent `json:"event"`
+}
+
+type WorkspaceFoldersChangeEvent struct {
+	Added   []WorkspaceFolder `json:"added"`
+	Removed []WorkspaceFolder `json:"removed"`
+}
+
+type WorkDoneProgressCreateParams struct {
+	Token string `json:"token"`
+}
+
+type ProgressParams struct {
+	Token string      `json:"token"`
+	Value interface{} `json:"value"`
+}
+
+type WorkDoneProgress struct {
+	Kind       string `json:"kind"`
+	Title      string `json:"title,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Percentage *int   `json:"percentage,omitempty"`
+}
+
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+	Position     Position         `json:"position"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// ViewContentsParams selects a view either by its key or by a position within its definition.
+type ViewContentsParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+	Key          string           `json:"key,omitempty"`
+	Position     *Position        `json:"position,omitempty"`
+}
+
+// ViewContents are the elements and relationships a view shows.
+type ViewContents struct {
+	Key           string             `json:"key,omitempty"`
+	Type          string             `json:"type"`
+	Elements      []ViewElement      `json:"elements"`
+	Relationships []ViewRelationship `json:"relationships"`
+}
+
+type ViewElement struct {
+	Identifier string   `json:"identifier,omitempty"`
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Tags       []string `json:"tags"`
+	Location   Location `json:"location"`
+}
+
+// ViewRelationship refers to its source and destination by their index in the elements of the view.
+type ViewRelationship struct {
+	Identifier  string   `json:"identifier,omitempty"`
+	Source      int      `json:"source"`
+	Destination int      `json:"destination"`
+	Description string   `json:"description,omitempty"`
+	Technology  string   `json:"technology,omitempty"`
+	Tags        []string `json:"tags"`
+	Location    Location `json:"location"`
+}
+
+type CompletionItemKind int
+
+var (
+	VariableCompletion CompletionItemKind = 6
+	ClassCompletion    CompletionItemKind = 7
+	KeywordCompletion  CompletionItemKind = 14
+)
+
+type CompletionItem struct {
+	Label  string             `json:"label"`
+	Kind   CompletionItemKind `json:"kind"`
+	Detail string             `json:"detail,omitempty"`
+}
+
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    Range         `json:"range"`
+}
+
+type DocumentLinkParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+type DocumentLink struct {
+	Range  Range  `json:"range"`
+	Target string `json:"target"`
+}
+
+type FoldingRangeParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+type FoldingRange struct {
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Kind      string `json:"kind,omitempty"`
+}
+
+type SelectionRangeParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+	Positions    []Position       `json:"positions"`
+}
+
+type SelectionRange struct {
+	Range  Range           `json:"range"`
+	Parent *SelectionRange `json:"parent,omitempty"`
+}
+
+type DocumentHighlightKind int
+
+var (
+	ReadHighlight  DocumentHighlightKind = 2
+	WriteHighlight DocumentHighlightKind = 3
+)
+
+type DocumentHighlight struct {
+	Range Range                 `json:"range"`
+	Kind  DocumentHighlightKind `json:"kind"`
+}
+
+type CodeActionParams struct {
+	TextDocument TextDocumentItem  `json:"textDocument"`
+	Range        Range             `json:"range"`
+	Context      CodeActionContext `json:"context"`
+}
+
+type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	// Kinds of the requested actions, every kind when empty
+	Only []string `json:"only,omitempty"`
+}
+
+type WorkspaceEdit struct {
+	Changes map[string][]TextEdit `json:"changes,omitempty"`
+	// CreateFile and TextDocumentEdit operations applied in order
+	DocumentChanges []interface{} `json:"documentChanges,omitempty"`
+}
+
+type CreateFile struct {
+	Kind string `json:"kind"`
+	URI  string `json:"uri"`
+}
+
+type DeleteFile struct {
+	Kind string `json:"kind"`
+	URI  string `json:"uri"`
+}
+
+type OptionalVersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version *int   `json:"version"`
+}
+
+type TextDocumentEdit struct {
+	TextDocument OptionalVersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit                              `json:"edits"`
+}
+
+type Command struct {
+	Title     string        `json:"title"`
+	Command   string        `json:"command"`
+	Arguments []interface{} `json:"arguments,omitempty"`
+}
+
+type CodeAction struct {
+	Title       string         `json:"title"`
+	Kind        string         `json:"kind"`
+	Diagnostics []Diagnostic   `json:"diagnostics,omitempty"`
+	Edit        *WorkspaceEdit `json:"edit,omitempty"`
+	Command     *Command       `json:"command,omitempty"`
+}
+
+type ExecuteCommandParams struct {
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments"`
+}
+
+// Arguments of the commands refactoring a range of a document.
+type RefactoringArguments struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+	Range        Range            `json:"range"`
+	// Deletes the included files when inlining an !include nothing else includes them
+	Delete bool `json:"delete,omitempty"`
+}
+
+type ApplyWorkspaceEditParams struct {
+	Label string        `json:"label"`
+	Edit  WorkspaceEdit `json:"edit"`
+}
+
+type ApplyWorkspaceEditResult struct {
+	Applied       bool   `json:"applied"`
+	FailureReason string `json:"failureReason,omitempty"`
+}
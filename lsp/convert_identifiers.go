@@ -0,0 +1,173 @@
+package lsp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+const convertIdentifiersCommand = "structurizr.convertIdentifiers"
+
+// Offers to convert the identifiers of the workspace on the line of the workspace or of its !identifiers directive.
+func (l *Lsp) convertIdentifiersAction(uri string, rng Range) *CodeAction {
+	content, ok := l.content[uri]
+	if !ok || content.Ast == nil || content.Workspace == nil {
+		return nil
+	}
+	workspace := workspaceOf(content.Ast)
+	if workspace == nil {
+		return nil
+	}
+	lines := []int{workspace.Location.Line}
+	for _, d := range identifiersDirectives(workspace) {
+		lines = append(lines, d.Location.Line)
+	}
+	if !slices.Contains(lines, rng.Start.Line) {
+		return nil
+	}
+	title := "Convert to hierarchical identifiers"
+	if hierarchical(content.Workspace) {
+		title = "Convert to flat identifiers"
+	}
+	return &CodeAction{Title: title, Kind: "refactor.rewrite", Command: &Command{
+		Title:     title,
+		Command:   convertIdentifiersCommand,
+		Arguments: []interface{}{RefactoringArguments{TextDocument: TextDocumentItem{URI: uri}, Range: rng}},
+	}}
+}
+
+// Switches the workspace between flat and hierarchical identifiers, the references to elements are rewritten to
+// their fully qualified or their unqualified identifiers in every file of the workspace.
+func (l *Lsp) convertIdentifiers(args RefactoringArguments) (*WorkspaceEdit, error) {
+	uri := args.TextDocument.URI
+	content, ok := l.content[uri]
+	if !ok || content.Ast == nil || content.Workspace == nil || content.Workspace.Model == nil || workspaceOf(content.Ast) == nil {
+		return nil, fmt.Errorf("No workspace to convert in %s", pathFromURI(uri))
+	}
+	ws := content.Workspace
+	toFlat := hierarchical(ws)
+	identifiers := make(map[*parser.Element]string)
+	for _, e := range ws.Model.Elements {
+		if e.Identifier == "" {
+			continue
+		}
+		if toFlat {
+			identifiers[e] = unqualified(e)
+		} else {
+			identifiers[e] = qualified(e)
+		}
+	}
+	if toFlat {
+		if collisions := collidingIdentifiers(identifiers); len(collisions) > 0 {
+			return nil, fmt.Errorf("Cannot convert to flat identifiers as they would collide: %s", strings.Join(collisions, ", "))
+		}
+	}
+	changes := make(map[string][]TextEdit)
+	add := func(rng parser.Range, text string) {
+		location := l.toLocation(rng)
+		edit := TextEdit{Range: location.Range, NewText: text}
+		if !slices.Contains(changes[location.URI], edit) {
+			changes[location.URI] = append(changes[location.URI], edit)
+		}
+	}
+	for _, ref := range ws.Model.Usages {
+		identifier, ok := identifiers[ref.Element]
+		if !ok || ref.Definition || !refersByName(ref) || isRemote(ref.Range.Start.Source) || strings.EqualFold(ref.Identifier, identifier) {
+			continue
+		}
+		if width := ref.Range.End.Pos - ref.Range.Start.Pos; ref.Range.Start.Line == ref.Range.End.Line && width == len([]rune(ref.Identifier))+2 {
+			// quoted identifiers stay quoted
+			identifier = "\"" + identifier + "\""
+		}
+		add(ref.Range, identifier)
+	}
+	mode := "hierarchical"
+	if toFlat {
+		mode = "flat"
+	}
+	workspace := workspaceOf(content.Ast)
+	directives := identifiersDirectives(workspace)
+	for _, d := range directives {
+		if option := d.Option(); option != nil {
+			add(parser.Range{Start: option.Location, End: option.End}, mode)
+		}
+	}
+	if len(directives) == 0 {
+		open, _ := blockOf(workspace)
+		if open == nil {
+			return nil, fmt.Errorf("The workspace has no block for the !identifiers directive")
+		}
+		at := parser.Location{Source: open.Location.Source, Line: open.Location.Line + 1}
+		lines := strings.Split(content.Text, "\n")
+		line := lines[workspace.Location.Line]
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		add(parser.Range{Start: at, End: at}, indent+indentUnit+"!identifiers "+mode+"\n")
+	}
+	return &WorkspaceEdit{Changes: changes}, nil
+}
+
+// Identifiers are hierarchical when either the workspace or the model says so.
+func hierarchical(ws *parser.Workspace) bool {
+	return ws.Identifiers == "hierarchical" || ws.Model != nil && ws.Model.Identifiers == "hierarchical"
+}
+
+// Returns the identifier of the element within its parent.
+func unqualified(e *parser.Element) string {
+	return e.Identifier[strings.LastIndex(e.Identifier, ".")+1:]
+}
+
+// Returns the identifier of the element prefixed with the identifier of its parent, like the analyser qualifies
+// the identifiers of hierarchical workspaces.
+func qualified(e *parser.Element) string {
+	if e.Parent == nil || e.Parent.Identifier == "" {
+		return unqualified(e)
+	}
+	return qualified(e.Parent) + "." + unqualified(e)
+}
+
+// References by an alias or by this are left as they are.
+func refersByName(ref *parser.Reference) bool {
+	identifier := strings.ToLower(ref.Identifier)
+	name := strings.ToLower(unqualified(ref.Element))
+	return identifier == name || strings.HasSuffix(identifier, "."+name)
+}
+
+// Lists the identifiers shared by several elements with the identifiers of the elements.
+func collidingIdentifiers(identifiers map[*parser.Element]string) []string {
+	elements := make(map[string][]string)
+	for e, identifier := range identifiers {
+		key := strings.ToLower(identifier)
+		elements[key] = append(elements[key], e.Identifier)
+	}
+	collisions := make([]string, 0)
+	for identifier, colliding := range elements {
+		if len(colliding) > 1 {
+			slices.Sort(colliding)
+			collisions = append(collisions, fmt.Sprintf("%s (%s)", identifier, strings.Join(colliding, ", ")))
+		}
+	}
+	slices.Sort(collisions)
+	return collisions
+}
+
+// Returns the !identifiers directives of the workspace and of its model.
+func identifiersDirectives(workspace *parser.ASTNode) []*parser.DirectiveNode {
+	directives := make([]*parser.DirectiveNode, 0)
+	collect := func(node *parser.ASTNode) {
+		if d, ok := node.Typed().(*parser.DirectiveNode); ok && d.Directive() == "!identifiers" {
+			directives = append(directives, d)
+		}
+	}
+	for _, c := range workspace.Children {
+		collect(c)
+		if c.Kind != parser.NodeModel {
+			continue
+		}
+		for _, m := range c.Children {
+			collect(m)
+		}
+	}
+	return directives
+}
@@ -0,0 +1,45 @@
+package lsp
+
+import (
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+// Navigates from an identifier to the definition of the element or relationship, which may be in an included file
+// or in the extended workspace.
+func (l *Lsp) handleDefinition(id int, params TextDocumentPositionParams) {
+	locations := make([]Location, 0)
+	if ref := l.referenceAt(params.TextDocument.URI, params.Position); ref != nil {
+		if ref.Element != nil {
+			locations = append(locations, l.toLocation(ref.Element.Definition))
+		} else if ref.Relationship != nil {
+			locations = append(locations, l.toLocation(ref.Relationship.Definition))
+		}
+	}
+	l.sendResponse(id, locations)
+}
+
+// Returns the reference under the position using the analysis of the document or of the documents including it.
+func (l *Lsp) referenceAt(uri string, position Position) *parser.Reference {
+	path := pathFromURI(uri)
+	loc := l.fromPosition(path, position)
+	for _, ws := range l.workspacesOf(path) {
+		if ref := ws.Model.ReferenceAt(loc); ref != nil {
+			return ref
+		}
+	}
+	return nil
+}
+
+// Returns the analysed workspaces containing the file, starting with its own analysis.
+func (l *Lsp) workspacesOf(path string) []*parser.Workspace {
+	workspaces := make([]*parser.Workspace, 0)
+	if content, ok := l.content[uriFromPath(path)]; ok && content.Workspace != nil && content.Workspace.Model != nil {
+		workspaces = append(workspaces, content.Workspace)
+	}
+	for _, root := range l.dependents(path) {
+		if ws := l.analyses[root].workspace; ws != nil && ws.Model != nil {
+			workspaces = append(workspaces, ws)
+		}
+	}
+	return workspaces
+}
@@ -1,28 +1,38 @@
 package lsp
 
 import (
+	"encoding/json"
 	"fmt"
-	"net/url"
 	"os"
+	"slices"
 
 	"github.com/tacsiazuma/structurizr-lsp/parser"
 	"github.com/tacsiazuma/structurizr-lsp/rpc"
 )
 
-func (l *Lsp) publishDiagnostics(diags []*parser.Diagnostic) {
+// Publishes the diagnostics of every file of the include graph, files without problems get an empty list to clear
+// the previously published ones.
+func (l *Lsp) publishDiagnostics(graph *parser.IncludeGraph, diags []*parser.Diagnostic) {
+	defer l.batchConversions()()
 	diagnostics := make(map[string][]*Diagnostic, 0)
+	sources := slices.Clone(graph.Files())
 	for _, diag := range diags {
-		diagnostics[diag.Location.Source] = append(diagnostics[diag.Location.Source], &Diagnostic{
-			Message: diag.Message,
-			Range:   Range{Start: Position{Character: diag.Location.Pos, Line: diag.Location.Line}, End: Position{Character: diag.Location.Pos, Line: diag.Location.Line}}})
+		if !slices.Contains(sources, diag.Location.Source) {
+			sources = append(sources, diag.Location.Source)
+		}
+		end := diag.End
+		if end.Source == "" {
+			end = diag.Location
+		}
+		diagnostics[diag.Location.Source] = append(diagnostics[diag.Location.Source], newDiagnostic(diag, Range{Start: l.toPosition(diag.Location), End: l.toPosition(end)}))
 	}
-	for k, v := range diagnostics {
-		uri := &url.URL{
-			Scheme: "file",
-			Path:   k,
+	for _, source := range sources {
+		v := diagnostics[source]
+		if v == nil {
+			v = make([]*Diagnostic, 0)
 		}
 		params := PublishDiagnosticsParams{
-			URI:         uri.String(),
+			URI:         uriFromPath(source),
 			Diagnostics: v,
 		}
 		notification := rpc.Notification{
@@ -35,16 +45,30 @@ func (l *Lsp) publishDiagnostics(diags []*parser.Diagnostic) {
 	}
 }
 
-func (l *Lsp) clearDiagnostics(s string) {
-	params := PublishDiagnosticsParams{
-		URI:         s,
-		Diagnostics: make([]*Diagnostic, 0),
+// Name of the server in the diagnostics it publishes, diagnostics of other sources are left alone.
+const diagnosticSource = "structurizr"
+
+func newDiagnostic(diag *parser.Diagnostic, r Range) *Diagnostic {
+	d := &Diagnostic{Range: r, Message: diag.Message, Source: diagnosticSource}
+	if diag.Code != "" {
+		d.Code, _ = json.Marshal(string(diag.Code))
 	}
-	notification := rpc.Notification{
-		Method: "textDocument/publishDiagnostics",
-		Params: params,
+	if len(diag.Data) > 0 {
+		d.Data, _ = json.Marshal(diag.Data)
+	}
+	return d
+}
+
+// Returns the code and data of a diagnostic published by this server, false for diagnostics of other sources or
+// with a code and data it can not have sent.
+func ownDiagnostic(diag Diagnostic) (parser.DiagnosticCode, []string, bool) {
+	var code string
+	var data []string
+	if diag.Source != diagnosticSource || len(diag.Code) == 0 || json.Unmarshal(diag.Code, &code) != nil {
+		return "", nil, false
 	}
-	if err := l.rpc.WriteMessage(notification); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to send error response: %v\n", err)
+	if len(diag.Data) > 0 && json.Unmarshal(diag.Data, &data) != nil {
+		return "", nil, false
 	}
+	return parser.DiagnosticCode(code), data, true
 }
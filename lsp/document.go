@@ -1,29 +1,117 @@
 package lsp
 
 import (
-	"strings"
+	"fmt"
+	"os"
+	"slices"
 
 	"github.com/tacsiazuma/structurizr-lsp/parser"
 )
 
+// Result of analysing a document together with the files it includes.
+type analysis struct {
+	workspace   *parser.Workspace
+	ast         *parser.ASTNode
+	graph       *parser.IncludeGraph
+	diagnostics []*parser.Diagnostic
+}
+
 func (l *Lsp) handleDidOpen(param DidOpenTextDocumentParams) {
-	a := parser.NewAnalyser(strings.TrimPrefix(param.TextDocument.URI, "file://"), param.TextDocument.Text)
-	_, ast, diags := a.Analyse()
-	l.registerContent(param.TextDocument.URI, param.TextDocument.Text, ast)
-	if len(diags) == 0 {
-		l.clearDiagnostics(param.TextDocument.URI)
-	} else {
-		l.publishDiagnostics(diags)
-	}
+	l.updateDocument(param.TextDocument.URI, param.TextDocument.Text)
 }
 
 func (l *Lsp) handleDidChange(param DidChangeTextDocumentParams) {
-	p := parser.NewAnalyser(strings.TrimPrefix(param.TextDocument.URI, "file://"), param.ContentChanges[0].Text)
-	_, ast, diags := p.Analyse()
-	l.registerContent(param.TextDocument.URI, param.ContentChanges[0].Text, ast)
-	if len(diags) == 0 {
-		l.clearDiagnostics(param.TextDocument.URI)
-	} else {
-		l.publishDiagnostics(diags)
+	l.updateDocument(param.TextDocument.URI, param.ContentChanges[0].Text)
+}
+
+func (l *Lsp) handleDidSave(param DidSaveTextDocumentParams) {
+	path := pathFromURI(param.TextDocument.URI)
+	l.cache.invalidate(path)
+	l.analyseDependents(path)
+}
+
+// Closed buffers are no longer served to includes, the content on disk is used again.
+func (l *Lsp) handleDidClose(param DidCloseTextDocumentParams) {
+	path := pathFromURI(param.TextDocument.URI)
+	delete(l.content, param.TextDocument.URI)
+	l.cache.invalidate(path)
+	l.analyseDependents(path)
+}
+
+// Analyses the document and every document including it. Diagnostics of an included file are published from the
+// analysis of the including documents, as on its own it is usually not a valid workspace.
+func (l *Lsp) updateDocument(uri, text string) {
+	path := pathFromURI(uri)
+	a := l.analyse(path, text)
+	l.registerContent(uri, text, a)
+	l.analyses[path] = a
+	if !l.includedElsewhere(path) {
+		l.publishDiagnostics(a.graph, a.diagnostics)
+	}
+	l.analyseDependents(path)
+	l.watchReferencedFiles()
+}
+
+func (l *Lsp) analyse(path, text string) *analysis {
+	analyser := parser.NewAnalyser(path, text, l.includer)
+	ws, ast, diags := analyser.Analyse()
+	return &analysis{workspace: ws, ast: ast, graph: analyser.IncludeGraph(), diagnostics: diags}
+}
+
+// Returns the analysed documents which include the file directly or transitively.
+func (l *Lsp) dependents(path string) []string {
+	roots := make([]string, 0)
+	for root, a := range l.analyses {
+		if root != path && a.graph.DependsOn(path) {
+			roots = append(roots, root)
+		}
+	}
+	slices.Sort(roots)
+	return roots
+}
+
+// Reports whether another analysed document includes the file, extended workspaces are analysed on their own.
+func (l *Lsp) includedElsewhere(path string) bool {
+	for root, a := range l.analyses {
+		if root != path && a.graph.Contains(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Re-analyses every document including the file and republishes their diagnostics.
+func (l *Lsp) analyseDependents(path string) {
+	for _, root := range l.dependents(path) {
+		l.analyseRoot(root)
+	}
+}
+
+// Analyses a document using its open buffer or the content on disk if it is not open.
+func (l *Lsp) analyseRoot(root string) {
+	uri := uriFromPath(root)
+	text, err := l.readDocument(root)
+	if err != nil {
+		l.logger.Printf("Cannot analyse %s: %v", root, err)
+		delete(l.analyses, root)
+		return
+	}
+	a := l.analyse(root, text)
+	if _, ok := l.content[uri]; ok {
+		l.registerContent(uri, text, a)
+	}
+	l.analyses[root] = a
+	l.publishDiagnostics(a.graph, a.diagnostics)
+	l.watchReferencedFiles()
+}
+
+func (l *Lsp) readDocument(path string) (string, error) {
+	if content, ok := l.content[uriFromPath(path)]; ok {
+		return content.Text, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read document: %w", err)
 	}
+	return string(b), nil
 }
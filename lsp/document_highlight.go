@@ -0,0 +1,48 @@
+package lsp
+
+import (
+	"slices"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+// Highlights the definition and the usages within the document of the element, relationship or archetype under
+// the position.
+func (l *Lsp) handleDocumentHighlight(id int, params TextDocumentPositionParams) {
+	highlights := make([]DocumentHighlight, 0)
+	path := pathFromURI(params.TextDocument.URI)
+	loc := l.fromPosition(path, params.Position)
+	for _, ws := range l.workspacesOf(path) {
+		ref := ws.Model.ReferenceAt(loc)
+		if ref == nil {
+			continue
+		}
+		for _, usage := range ws.Model.Usages {
+			if usage.Range.Start.Source != path || !sameTarget(ref, usage) {
+				continue
+			}
+			highlight := DocumentHighlight{Range: l.toLocation(usage.Range).Range, Kind: ReadHighlight}
+			if usage.Definition {
+				highlight.Kind = WriteHighlight
+			}
+			if !slices.Contains(highlights, highlight) {
+				highlights = append(highlights, highlight)
+			}
+		}
+		break
+	}
+	l.sendResponse(id, highlights)
+}
+
+// Reports whether the references point to the same element, relationship or archetype.
+func sameTarget(a *parser.Reference, b *parser.Reference) bool {
+	switch {
+	case a.Element != nil:
+		return a.Element == b.Element
+	case a.Relationship != nil:
+		return a.Relationship == b.Relationship
+	case a.Archetype != nil:
+		return a.Archetype == b.Archetype
+	}
+	return false
+}
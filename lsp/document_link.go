@@ -0,0 +1,83 @@
+package lsp
+
+import (
+	"os"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+// Makes the paths of directives, themes and urls of the document clickable.
+func (l *Lsp) handleDocumentLink(id int, params DocumentLinkParams) {
+	links := make([]DocumentLink, 0)
+	if content, ok := l.content[params.TextDocument.URI]; ok && content.Ast != nil {
+		links = l.findDocumentLinks(content.Ast, pathFromURI(params.TextDocument.URI), links)
+	}
+	l.sendResponse(id, links)
+}
+
+// Collects the links of the nodes within the source, included statements belong to their own files.
+func (l *Lsp) findDocumentLinks(node *parser.ASTNode, source string, links []DocumentLink) []DocumentLink {
+	if node.Location.Source == source {
+		_, url := node.Typed().(*parser.URLNode)
+		for _, t := range linkTargets(node) {
+			if target := linkTarget(t, url); target != "" {
+				links = append(links, DocumentLink{Range: l.toLocation(linkRange(t)).Range, Target: target})
+			}
+		}
+	}
+	for _, c := range node.Children {
+		links = l.findDocumentLinks(c, source, links)
+	}
+	return links
+}
+
+// Returns the tokens of the node which may be links. Paths of directives are links, directory includes link to the
+// directory itself, scripts and plugins only when they are files.
+func linkTargets(node *parser.ASTNode) []*parser.Token {
+	switch n := node.Typed().(type) {
+	case *parser.DirectiveNode:
+		if path := n.Path(); path != nil {
+			return []*parser.Token{path}
+		}
+		if file := n.File(); file != nil {
+			return []*parser.Token{file}
+		}
+	case *parser.ThemeNode:
+		return n.Themes()
+	case *parser.URLNode:
+		if url := n.URL(); url != nil {
+			return []*parser.Token{url}
+		}
+	}
+	return nil
+}
+
+// Resolves the token relative to its source. URLs are links as they are, other paths only when they exist, unless
+// the path is of a directive which always refers to a file.
+func linkTarget(t *parser.Token, remoteOnly bool) string {
+	resolved := parser.ResolvePath(t.Location.Source, t.Content)
+	if isRemote(resolved) {
+		return resolved
+	}
+	if remoteOnly {
+		return ""
+	}
+	if t.Role != parser.RolePath {
+		if _, err := os.Stat(resolved); err != nil {
+			return ""
+		}
+	}
+	return uriFromPath(resolved)
+}
+
+// Links of quoted paths cover the content of the string only.
+func linkRange(t *parser.Token) parser.Range {
+	rng := parser.Range{Start: t.Location, End: t.End}
+	if t.Type == parser.TokenString {
+		rng.Start.Pos++
+		if t.Terminated {
+			rng.End.Pos--
+		}
+	}
+	return rng
+}
@@ -0,0 +1,128 @@
+package lsp
+
+import (
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+type PositionEncodingKind string
+
+const (
+	UTF8  PositionEncodingKind = "utf-8"
+	UTF16 PositionEncodingKind = "utf-16"
+	UTF32 PositionEncodingKind = "utf-32"
+)
+
+// Picks the first encoding offered by the client which we support, UTF-16 is mandatory for every client
+// so that is the fallback.
+func negotiateEncoding(offered []PositionEncodingKind) PositionEncodingKind {
+	for _, e := range offered {
+		if e == UTF8 || e == UTF16 || e == UTF32 {
+			return e
+		}
+	}
+	return UTF16
+}
+
+// Converts a rune based column of a line into code units of the given encoding.
+func (e PositionEncodingKind) fromRunes(line string, col int) int {
+	if e == UTF32 {
+		return col
+	}
+	units := 0
+	for _, r := range line {
+		if col <= 0 {
+			break
+		}
+		units += e.width(r)
+		col--
+	}
+	// positions past the end of the line are kept as they are
+	return units + col
+}
+
+// Converts code units of the given encoding into a rune based column of a line.
+func (e PositionEncodingKind) toRunes(line string, units int) int {
+	if e == UTF32 {
+		return units
+	}
+	col := 0
+	for _, r := range line {
+		if units <= 0 {
+			return col
+		}
+		units -= e.width(r)
+		col++
+	}
+	if units > 0 {
+		col += units
+	}
+	return col
+}
+
+// Returns the length of a whole line in code units of the given encoding.
+func (e PositionEncodingKind) length(line string) int {
+	return e.fromRunes(line, utf8.RuneCountInString(line))
+}
+
+func (e PositionEncodingKind) width(r rune) int {
+	switch e {
+	case UTF8:
+		return utf8.RuneLen(r)
+	case UTF16:
+		return len(utf16.Encode([]rune{r}))
+	}
+	return 1
+}
+
+// Converts a parser location into an LSP position using the negotiated encoding.
+func (l *Lsp) toPosition(loc parser.Location) Position {
+	return Position{Line: loc.Line, Character: l.encoding.fromRunes(l.sourceLine(loc.Source, loc.Line), loc.Pos)}
+}
+
+// Converts an LSP position of a source file into a parser location.
+func (l *Lsp) fromPosition(source string, pos Position) parser.Location {
+	return parser.Location{Source: source, Line: pos.Line, Pos: l.encoding.toRunes(l.sourceLine(source, pos.Line), pos.Character)}
+}
+
+// Converts a parser range into an LSP location.
+func (l *Lsp) toLocation(rng parser.Range) Location {
+	return Location{URI: uriFromPath(rng.Start.Source), Range: Range{Start: l.toPosition(rng.Start), End: l.toPosition(rng.End)}}
+}
+
+// Starts a batch of conversions reading the files which are not open only once, the returned function ends it.
+// Nested batches belong to the outermost one. Open buffers are split into lines whenever they change instead.
+func (l *Lsp) batchConversions() func() {
+	if l.lines != nil {
+		return func() {}
+	}
+	l.lines = make(map[string][]string)
+	return func() { l.lines = nil }
+}
+
+// Returns the given line of a source file, preferring the open buffer over the content on disk.
+func (l *Lsp) sourceLine(source string, line int) string {
+	if l.encoding == UTF32 {
+		return ""
+	}
+	var lines []string
+	if content, ok := l.content[uriFromPath(source)]; ok {
+		lines = content.Lines
+	} else if cached, ok := l.lines[source]; ok {
+		lines = cached
+	} else {
+		b, _ := os.ReadFile(source)
+		lines = strings.Split(string(b), "\n")
+		if l.lines != nil {
+			l.lines[source] = lines
+		}
+	}
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[line], "\r")
+}
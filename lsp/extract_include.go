@@ -0,0 +1,180 @@
+package lsp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+const extractIncludeCommand = "structurizr.extractInclude"
+
+// Statements of a block moved into a new file.
+type extraction struct {
+	statements []*parser.ASTNode
+	// lines of the statements, the last one included
+	first int
+	last  int
+	// name of the new file without its extension
+	name string
+}
+
+// Offers to extract the statements of the model covered by the range, the edit is computed when the command is
+// executed. The client has to be able to create the new file.
+func (l *Lsp) extractIncludeAction(uri string, rng Range) *CodeAction {
+	if !l.supportsDocumentChanges("create") || l.extraction(uri, rng) == nil {
+		return nil
+	}
+	title := "Extract to !include file"
+	return &CodeAction{Title: title, Kind: "refactor.extract", Command: &Command{
+		Title:     title,
+		Command:   extractIncludeCommand,
+		Arguments: []interface{}{RefactoringArguments{TextDocument: TextDocumentItem{URI: uri}, Range: rng}},
+	}}
+}
+
+// Moves the statements into a new file next to the document and includes it in their place.
+func (l *Lsp) extractInclude(args RefactoringArguments) (*WorkspaceEdit, error) {
+	uri := args.TextDocument.URI
+	ex := l.extraction(uri, args.Range)
+	if ex == nil {
+		return nil, fmt.Errorf("No statements of the model to extract")
+	}
+	path := pathFromURI(uri)
+	lines := strings.Split(l.content[uri].Text, "\n")
+	indent := lines[ex.first][:len(lines[ex.first])-len(strings.TrimLeft(lines[ex.first], " \t"))]
+	extracted := make([]string, 0, ex.last-ex.first+1)
+	for _, line := range lines[ex.first : ex.last+1] {
+		extracted = append(extracted, strings.TrimPrefix(line, indent))
+	}
+	file := l.includeFileName(filepath.Dir(path), ex.name)
+	target := uriFromPath(filepath.Join(filepath.Dir(path), file))
+	replaced := parser.Range{Start: parser.Location{Source: path, Line: ex.first}, End: parser.Location{Source: path, Line: ex.last + 1}}
+	return &WorkspaceEdit{DocumentChanges: []interface{}{
+		CreateFile{Kind: "create", URI: target},
+		TextDocumentEdit{
+			TextDocument: OptionalVersionedTextDocumentIdentifier{URI: target},
+			Edits:        []TextEdit{{NewText: strings.Join(extracted, "\n") + "\n"}},
+		},
+		TextDocumentEdit{
+			TextDocument: OptionalVersionedTextDocumentIdentifier{URI: uri},
+			Edits:        []TextEdit{{Range: l.toLocation(replaced).Range, NewText: indent + "!include " + file + "\n"}},
+		},
+	}}, nil
+}
+
+// Returns the statements of the model fully covered by the lines of the range, nil when the range covers a part of
+// a statement or statements outside of the model.
+func (l *Lsp) extraction(uri string, rng Range) *extraction {
+	content, ok := l.content[uri]
+	if !ok || content.Ast == nil {
+		return nil
+	}
+	last := rng.End.Line
+	if rng.End.Character == 0 && last > rng.Start.Line {
+		// the selection of whole lines ends at the start of the next line
+		last--
+	}
+	block, statements := selectedStatements(content.Ast, pathFromURI(uri), rng.Start.Line, last)
+	if !slices.ContainsFunc(statements, func(n *parser.ASTNode) bool { return !isInclude(n) }) {
+		// extracting includes only would include them from another file
+		return nil
+	}
+	ex := &extraction{statements: statements, first: statements[0].Range.Start.Line, last: statements[len(statements)-1].Range.End.Line}
+	ex.name = identifierOf(block)
+	if ex.name == "" {
+		ex.name = identifierOf(statements[0])
+	}
+	if ex.name == "" {
+		ex.name = string(block.Kind)
+	}
+	return ex
+}
+
+// Finds the block whose statements are covered by the lines, descending into the only statement partly covered.
+func selectedStatements(node *parser.ASTNode, source string, first int, last int) (*parser.ASTNode, []*parser.ASTNode) {
+	overlapping := make([]*parser.ASTNode, 0)
+	covered := true
+	for _, c := range node.Children {
+		if c.Kind == parser.NodeBlockStart || c.Kind == parser.NodeBlockEnd || c.Range.Start.Source != source {
+			continue
+		}
+		if c.Range.Start.Line <= last && c.Range.End.Line >= first {
+			overlapping = append(overlapping, c)
+			covered = covered && c.Range.Start.Line >= first && c.Range.End.Line <= last
+		}
+	}
+	if len(overlapping) == 0 {
+		return nil, nil
+	}
+	if !covered {
+		if len(overlapping) == 1 {
+			return selectedStatements(blockOwnerOf(overlapping[0]), source, first, last)
+		}
+		return nil, nil
+	}
+	open, close := blockOf(node)
+	if !modelBlock(node) || open == nil || close == nil || open.Location.Line >= first || close.Location.Line <= last {
+		return nil, nil
+	}
+	return node, overlapping
+}
+
+// Reports whether the node is an !include directive.
+func isInclude(node *parser.ASTNode) bool {
+	d, ok := node.Typed().(*parser.DirectiveNode)
+	return ok && d.Include()
+}
+
+// Blocks of assignments belong to the assigned element.
+func blockOwnerOf(node *parser.ASTNode) *parser.ASTNode {
+	if node.Kind == parser.NodeAssignment && len(node.Children) > 1 {
+		return node.Children[len(node.Children)-1]
+	}
+	return node
+}
+
+// Reports whether the block contains elements and relationships.
+func modelBlock(node *parser.ASTNode) bool {
+	switch n := node.Typed().(type) {
+	case *parser.ModelNode, *parser.ElementNode:
+		return true
+	case *parser.DirectiveNode:
+		return n.ElementDirective()
+	}
+	return false
+}
+
+// Returns the identifier the element or relationship is assigned to, empty when it is not assigned.
+func identifierOf(node *parser.ASTNode) string {
+	var identifier *parser.ASTNode
+	switch n := blockOwnerOf(node).Typed().(type) {
+	case *parser.ElementNode:
+		identifier = n.Identifier()
+	case *parser.RelationshipNode:
+		identifier = n.Identifier()
+	}
+	if identifier == nil {
+		return ""
+	}
+	return identifier.Content
+}
+
+// Returns a file name in the directory which is neither on disk nor open.
+func (l *Lsp) includeFileName(dir string, name string) string {
+	for i := 1; ; i++ {
+		file := name + ".dsl"
+		if i > 1 {
+			file = fmt.Sprintf("%s-%d.dsl", name, i)
+		}
+		path := filepath.Join(dir, file)
+		if _, err := os.Stat(path); err != nil {
+			if _, open := l.content[uriFromPath(path)]; !open {
+				return file
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package lsp
+
+import (
+	"slices"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+const (
+	foldComment = "comment"
+	foldRegion  = "region"
+)
+
+// Folds the blocks, the comments spanning lines and the runs of !include directives of the document.
+func (l *Lsp) handleFoldingRange(id int, params FoldingRangeParams) {
+	ranges := make([]FoldingRange, 0)
+	if content, ok := l.content[params.TextDocument.URI]; ok {
+		path := pathFromURI(params.TextDocument.URI)
+		includes := make(map[int]bool)
+		if content.Ast != nil {
+			ranges = blockFoldingRanges(content.Ast, path, ranges)
+			includeLines(content.Ast, path, includes)
+		}
+		ranges = append(ranges, tokenFoldingRanges(parser.Tokenize(path, content.Text), includes)...)
+	}
+	slices.SortStableFunc(ranges, func(a, b FoldingRange) int { return a.StartLine - b.StartLine })
+	l.sendResponse(id, ranges)
+}
+
+// Folds the lines between the braces of the blocks, the closing brace stays visible.
+func blockFoldingRanges(node *parser.ASTNode, source string, ranges []FoldingRange) []FoldingRange {
+	open, close := blockOf(node)
+	if open != nil && close != nil && open.Location.Source == source && close.Location.Source == source && close.Location.Line-1 > open.Location.Line {
+		ranges = append(ranges, FoldingRange{StartLine: open.Location.Line, EndLine: close.Location.Line - 1, Kind: foldRegion})
+	}
+	for _, c := range node.Children {
+		ranges = blockFoldingRanges(c, source, ranges)
+	}
+	return ranges
+}
+
+// Collects the lines of the !include directives within the source.
+func includeLines(node *parser.ASTNode, source string, lines map[int]bool) {
+	if node.Location.Source == source && isInclude(node) {
+		lines[node.Location.Line] = true
+	}
+	for _, c := range node.Children {
+		includeLines(c, source, lines)
+	}
+}
+
+// Folds the multi-line comments, consecutive line comments and consecutive lines starting with an !include directive.
+func tokenFoldingRanges(tokens []parser.Token, includes map[int]bool) []FoldingRange {
+	ranges := make([]FoldingRange, 0)
+	var run *FoldingRange
+	// runs are extended by the same kind of line directly following them
+	extend := func(line int, kind string) {
+		if run != nil && run.Kind == kind && run.EndLine == line-1 {
+			run.EndLine = line
+			return
+		}
+		if run != nil && run.EndLine > run.StartLine {
+			ranges = append(ranges, *run)
+		}
+		run = &FoldingRange{StartLine: line, EndLine: line, Kind: kind}
+	}
+	first := true
+	for _, t := range tokens {
+		switch {
+		case t.Type == parser.TokenNewline:
+			first = true
+			continue
+		case t.Type == parser.TokenComment && t.End.Line > t.Location.Line:
+			ranges = append(ranges, FoldingRange{StartLine: t.Location.Line, EndLine: t.End.Line, Kind: foldComment})
+		case t.Type == parser.TokenComment && first:
+			extend(t.Location.Line, foldComment)
+		case includes[t.Location.Line] && first:
+			extend(t.Location.Line, foldRegion)
+		}
+		first = false
+	}
+	if run != nil && run.EndLine > run.StartLine {
+		ranges = append(ranges, *run)
+	}
+	return ranges
+}
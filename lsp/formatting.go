@@ -48,7 +48,7 @@ func (l *Lsp) handleFormatting(id int, param FormattingParams) {
 		edits = append(edits, TextEdit{
 			Range: Range{
 				Start: Position{Line: lineNum, Character: 0},
-				End:   Position{Line: lineNum, Character: len(originalLine)},
+				End:   Position{Line: lineNum, Character: l.encoding.length(originalLine)},
 			},
 			NewText: formattedLine,
 		})
@@ -59,7 +59,9 @@ func (l *Lsp) handleFormatting(id int, param FormattingParams) {
 		l.logger.Println("error reading input: " + err.Error())
 		return
 	}
-	l.registerContent(param.TextDocument.URI, sb.String(), content.Ast) // update the content after formatting
+	content.Text = sb.String()
+	content.Lines = strings.Split(content.Text, "\n")
+	l.content[param.TextDocument.URI] = *content // update the content after formatting
 	response := rpc.Response{
 		Jsonrpc: "2.0",
 		ID:      id,
@@ -0,0 +1,79 @@
+package lsp
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+// Describes the element, relationship or archetype under the position.
+func (l *Lsp) handleHover(id int, params TextDocumentPositionParams) {
+	ref := l.referenceAt(params.TextDocument.URI, params.Position)
+	if ref == nil {
+		l.sendResponse(id, nil)
+		return
+	}
+	var value string
+	switch {
+	case ref.Element != nil:
+		e := ref.Element
+		value = fmt.Sprintf("**%s** `%s`", e.Name, elementKind(e.Type, e.Metadata))
+		value += describe(e.Details)
+	case ref.Relationship != nil:
+		r := ref.Relationship
+		value = fmt.Sprintf("**%s** → **%s**", r.Source.Name, r.Destination.Name)
+		value += describe(r.Details)
+	case ref.Archetype != nil:
+		a := ref.Archetype
+		value = fmt.Sprintf("**%s** archetype of `%s`", a.Name, elementKind(a.Type, a.Metadata))
+		value += describe(a.Details)
+	}
+	l.sendResponse(id, Hover{Contents: MarkupContent{Kind: "markdown", Value: value}, Range: l.toLocation(ref.Range).Range})
+}
+
+// Custom elements are known by their metadata.
+func elementKind(kind string, metadata string) string {
+	if kind == "->" {
+		return "relationship"
+	}
+	if metadata != "" {
+		return kind + ": " + metadata
+	}
+	return kind
+}
+
+// Lists the details as markdown paragraphs, empty details are left out.
+func describe(d parser.Details) string {
+	var sb strings.Builder
+	if d.Description != "" {
+		sb.WriteString("\n\n" + d.Description)
+	}
+	if d.Technology != "" {
+		sb.WriteString("\n\nTechnology: " + d.Technology)
+	}
+	if len(d.Tags) > 0 {
+		sb.WriteString("\n\nTags: " + strings.Join(d.Tags, ", "))
+	}
+	if d.URL != "" {
+		sb.WriteString("\n\nURL: " + d.URL)
+	}
+	if len(d.Properties) > 0 {
+		sb.WriteString("\n\nProperties:\n")
+		for _, name := range slices.Sorted(maps.Keys(d.Properties)) {
+			sb.WriteString(fmt.Sprintf("\n- %s: %s", name, d.Properties[name]))
+		}
+	}
+	if len(d.Perspectives) > 0 {
+		sb.WriteString("\n\nPerspectives:\n")
+		for _, p := range d.Perspectives {
+			sb.WriteString(fmt.Sprintf("\n- %s: %s", p.Name, p.Description))
+			if p.Value != "" {
+				sb.WriteString(" (" + p.Value + ")")
+			}
+		}
+	}
+	return sb.String()
+}
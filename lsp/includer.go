@@ -0,0 +1,115 @@
+package lsp
+
+import (
+	"path/filepath"
+	"slices"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+// Includes are resolved from the open buffers first, then from the files read earlier and finally from the disk or
+// the network.
+func (l *Lsp) setIncluder(in parser.Includer) {
+	l.cache = newCachingIncluder(in, l.watched)
+	l.includer = newOverlayIncluder(l, l.cache)
+}
+
+// Serves included files from the open editor buffers first, so unsaved changes of an included file are part of
+// the analysis of the files including it.
+type overlayIncluder struct {
+	lsp      *Lsp
+	fallback parser.Includer
+}
+
+func newOverlayIncluder(l *Lsp, fallback parser.Includer) *overlayIncluder {
+	return &overlayIncluder{lsp: l, fallback: fallback}
+}
+
+func (o *overlayIncluder) Include(included string) ([]parser.IncludedFile, error) {
+	if text, ok := o.buffer(included); ok {
+		return []parser.IncludedFile{{Path: included, Content: text}}, nil
+	}
+	return o.overlay(o.fallback.Include(included))
+}
+
+// Documentation like the decisions of !adrs may be edited in the editor too.
+func (o *overlayIncluder) Documentation(path string) ([]parser.IncludedFile, error) {
+	if text, ok := o.buffer(path); ok {
+		return []parser.IncludedFile{{Path: path, Content: text}}, nil
+	}
+	return o.overlay(o.fallback.Documentation(path))
+}
+
+// Replaces the content of the files of a directory which are open.
+func (o *overlayIncluder) overlay(files []parser.IncludedFile, err error) ([]parser.IncludedFile, error) {
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		if text, ok := o.buffer(files[i].Path); ok {
+			files[i].Content = text
+		}
+	}
+	return files, nil
+}
+
+func (o *overlayIncluder) buffer(path string) (string, bool) {
+	content, ok := o.lsp.content[uriFromPath(path)]
+	if !ok {
+		return "", false
+	}
+	return content.Text, true
+}
+
+// Keeps the local files read by the fallback until they are invalidated by a change on disk, so re-analysing many
+// documents does not read the same files over and over. Without watching the files the cache would go stale, so it
+// is only enabled when the client notifies about changes on disk.
+type cachingIncluder struct {
+	fallback parser.Includer
+	// files are cached only while changes of them are reported
+	watched       func(path string) bool
+	files         map[string][]parser.IncludedFile
+	documentation map[string][]parser.IncludedFile
+}
+
+func newCachingIncluder(fallback parser.Includer, watched func(path string) bool) *cachingIncluder {
+	return &cachingIncluder{fallback: fallback, watched: watched, files: make(map[string][]parser.IncludedFile), documentation: make(map[string][]parser.IncludedFile)}
+}
+
+func (c *cachingIncluder) Include(included string) ([]parser.IncludedFile, error) {
+	if !c.watched(included) {
+		return c.fallback.Include(included)
+	}
+	if files, ok := c.files[included]; ok {
+		return slices.Clone(files), nil
+	}
+	files, err := c.fallback.Include(included)
+	if err != nil {
+		return nil, err
+	}
+	c.files[included] = slices.Clone(files)
+	return files, nil
+}
+
+func (c *cachingIncluder) Documentation(path string) ([]parser.IncludedFile, error) {
+	if !c.watched(path) {
+		return c.fallback.Documentation(path)
+	}
+	if files, ok := c.documentation[path]; ok {
+		return slices.Clone(files), nil
+	}
+	files, err := c.fallback.Documentation(path)
+	if err != nil {
+		return nil, err
+	}
+	c.documentation[path] = slices.Clone(files)
+	return files, nil
+}
+
+// Drops the cached file and the directory containing it.
+func (c *cachingIncluder) invalidate(path string) {
+	for _, cached := range []map[string][]parser.IncludedFile{c.files, c.documentation} {
+		delete(cached, path)
+		delete(cached, filepath.Dir(path))
+	}
+}
@@ -14,38 +14,38 @@ func (l *Lsp) handleInlayHint(id int, param InlayHintParams) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	hints := l.findInlayHints(content.Ast, param.Range)
+	hints := l.findInlayHints(content.Ast, pathFromURI(param.TextDocument.URI), param.Range)
 	if len(hints) > 0 {
 		l.publishInlayHints(id, hints)
 	}
 }
 
-var inlayTokens = []parser.TokenType{
-	parser.TokenName, parser.TokenDescription, parser.TokenValue,
+var inlayRoles = []parser.TokenRole{
+	parser.RoleName, parser.RoleDescription, parser.RoleTechnology, parser.RoleValue,
 }
 
-func (l *Lsp) findInlayHints(node *parser.ASTNode, rng Range) []InlayHint {
+func (l *Lsp) findInlayHints(node *parser.ASTNode, source string, rng Range) []InlayHint {
 	hints := make([]InlayHint, 0)
-	for _, v := range inlayTokens {
-		if v == node.Token.Type {
+	for _, v := range inlayRoles {
+		if v == node.Token.Role && node.Location.Source == source {
 			hints = append(hints, InlayHint{
-				Label:    fmt.Sprintf("%s: ", node.Token.Type),
-				Position: Position{Line: node.Location.Line, Character: node.Location.Pos},
+				Label:    fmt.Sprintf("%s: ", node.Token.Role),
+				Position: l.toPosition(node.Location),
 			})
 		}
 	}
 	for _, attribute := range node.Attributes {
-		for _, v := range inlayTokens {
-			if v == attribute.Type {
+		for _, v := range inlayRoles {
+			if v == attribute.Role && attribute.Location.Source == source {
 				hints = append(hints, InlayHint{
-					Label:    fmt.Sprintf("%s: ", attribute.Type),
-					Position: Position{Line: attribute.Location.Line, Character: attribute.Location.Pos},
+					Label:    fmt.Sprintf("%s: ", attribute.Role),
+					Position: l.toPosition(attribute.Location),
 				})
 			}
 		}
 	}
 	for _, child := range node.Children {
-		hints = append(hints, l.findInlayHints(child, rng)...)
+		hints = append(hints, l.findInlayHints(child, source, rng)...)
 	}
 	return hints
 }
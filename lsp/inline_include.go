@@ -0,0 +1,175 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+const inlineIncludeCommand = "structurizr.inlineInclude"
+
+// Offers to inline the !include directive on the first line of the range, deleting the included files is offered
+// too when nothing else includes them and the client is able to delete files.
+func (l *Lsp) inlineIncludeActions(uri string, rng Range) []CodeAction {
+	actions := make([]CodeAction, 0)
+	in := l.includeAt(uri, rng.Start.Line)
+	if in == nil || !l.supportsDocumentChanges() {
+		return actions
+	}
+	args := RefactoringArguments{TextDocument: TextDocumentItem{URI: uri}, Range: rng}
+	titles := []string{"Inline !include"}
+	if l.includedOnlyBy(in) && l.supportsDocumentChanges("delete") {
+		titles = append(titles, "Inline !include and delete the included files")
+	}
+	for i, title := range titles {
+		args.Delete = i > 0
+		actions = append(actions, CodeAction{Title: title, Kind: "refactor.inline", Command: &Command{
+			Title:     title,
+			Command:   inlineIncludeCommand,
+			Arguments: []interface{}{args},
+		}})
+	}
+	return actions
+}
+
+// Replaces the !include directive with the content of the included files indented like the directive.
+func (l *Lsp) inlineInclude(args RefactoringArguments) (*WorkspaceEdit, error) {
+	uri := args.TextDocument.URI
+	in := l.includeAt(uri, args.Range.Start.Line)
+	if in == nil {
+		return nil, fmt.Errorf("No !include to inline")
+	}
+	path := pathFromURI(uri)
+	lines := strings.Split(l.content[uri].Text, "\n")
+	line := in.Directive.Location.Line
+	indent := lines[line][:len(lines[line])-len(strings.TrimLeft(lines[line], " \t"))]
+	var sb strings.Builder
+	for _, file := range in.Files {
+		text, err := l.readDocument(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, included := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+			if strings.TrimSpace(included) != "" {
+				sb.WriteString(indent + rebasePaths(included, filepath.Dir(file), filepath.Dir(path)))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	replaced := parser.Range{Start: parser.Location{Source: path, Line: line}, End: parser.Location{Source: path, Line: line + 1}}
+	if line+1 >= len(lines) {
+		// the directive is on the last line without a line break
+		replaced.End = parser.Location{Source: path, Line: line, Pos: len([]rune(lines[line]))}
+	}
+	changes := []interface{}{TextDocumentEdit{
+		TextDocument: OptionalVersionedTextDocumentIdentifier{URI: uri},
+		Edits:        []TextEdit{{Range: l.toLocation(replaced).Range, NewText: sb.String()}},
+	}}
+	if args.Delete && l.includedOnlyBy(in) && l.supportsDocumentChanges("delete") {
+		for _, file := range in.Files {
+			changes = append(changes, DeleteFile{Kind: "delete", URI: uriFromPath(file)})
+		}
+	}
+	return &WorkspaceEdit{DocumentChanges: changes}, nil
+}
+
+// Returns the local !include directive of the document on the line.
+func (l *Lsp) includeAt(uri string, line int) *parser.Include {
+	content, ok := l.content[uri]
+	if !ok || content.Graph == nil {
+		return nil
+	}
+	for _, in := range content.Graph.Includes(pathFromURI(uri)) {
+		if in.Directive.Location.Line == line && len(in.Files) > 0 && !isRemote(in.Path) {
+			return in
+		}
+	}
+	return nil
+}
+
+// Reports whether the directive is the only one including its files in the analysed documents.
+func (l *Lsp) includedOnlyBy(include *parser.Include) bool {
+	for _, a := range l.analyses {
+		for _, source := range a.graph.Files() {
+			for _, in := range a.graph.Includes(source) {
+				if in.Directive.Location == include.Directive.Location {
+					continue
+				}
+				for _, file := range include.Files {
+					if slices.Contains(in.Files, file) {
+						return false
+					}
+				}
+			}
+		}
+	}
+	return true
+}
+
+// Keywords followed by paths relative to the file they are in.
+var relativePaths = map[string]bool{
+	"!include": true,
+	"!docs":    true,
+	"!adrs":    true,
+	"!script":  true,
+	"theme":    true,
+	"themes":   true,
+	"image":    true,
+	"plantuml": true,
+	"mermaid":  true,
+}
+
+// Keeps the relative paths of an inlined line pointing to the same files once it is moved to another directory. Only
+// the tokens of the paths are replaced, the rest of the line is kept as it is.
+func rebasePaths(line string, from string, to string) string {
+	tokens := make([]parser.Token, 0)
+	for _, t := range parser.Tokenize("", line) {
+		if t.Type != parser.TokenComment && t.Type != parser.TokenNewline {
+			tokens = append(tokens, t)
+		}
+	}
+	if from == to || len(tokens) < 2 || !relativePaths[tokens[0].Content] {
+		return line
+	}
+	keyword := tokens[0].Content
+	if keyword == "image" && len(tokens) != 2 || keyword == "!script" && len(tokens) > 2 {
+		// image views have a scope and a key, inline scripts a language and a block instead of a path
+		return line
+	}
+	paths := tokens[1:2]
+	if keyword == "themes" {
+		paths = tokens[1:]
+	}
+	runes := []rune(line)
+	// replaced from the end of the line so the positions of the preceding paths stay valid
+	for i := len(paths) - 1; i >= 0; i-- {
+		path := paths[i]
+		if path.Type != parser.TokenKeyword && path.Type != parser.TokenString {
+			continue
+		}
+		rebased, ok := rebasePath(path.Content, from, to)
+		if !ok || (keyword == "theme" || keyword == "themes") && path.Content == "default" {
+			continue
+		}
+		if path.Type == parser.TokenString || strings.ContainsAny(rebased, " \t") {
+			rebased = "\"" + rebased + "\""
+		}
+		runes = slices.Concat(runes[:path.Location.Pos], []rune(rebased), runes[path.End.Pos:])
+	}
+	return string(runes)
+}
+
+// Returns the path relative to the other directory, false for remote and absolute paths.
+func rebasePath(path string, from string, to string) (string, bool) {
+	if path == "" || isRemote(path) || filepath.IsAbs(path) {
+		return "", false
+	}
+	rebased, err := filepath.Rel(to, filepath.Join(from, path))
+	if err != nil {
+		return "", false
+	}
+	return filepath.ToSlash(rebased), true
+}
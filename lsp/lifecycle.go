@@ -3,21 +3,50 @@ package lsp
 import (
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/tacsiazuma/structurizr-lsp/parser"
 	"github.com/tacsiazuma/structurizr-lsp/rpc"
 )
 
-func (l *Lsp) handleInitialize(req rpc.Request) {
+func (l *Lsp) handleInitialize(req rpc.Request, params InitializeParams) {
 	l.initialized = true
+	l.encoding = negotiateEncoding(params.Capabilities.General.PositionEncodings)
+	l.watchFiles = params.Capabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration
+	l.workspaceEdit = params.Capabilities.Workspace.WorkspaceEdit
+	l.progress = params.Capabilities.Window.WorkDoneProgress
+	l.folders = workspaceFolders(params)
+	l.setIncluder(parser.NewRemoteIncluder(&parser.FSIncluder{}, params.InitializationOptions.remoteOptions()))
 	// Respond with basic server capabilities
 	capabilities := map[string]interface{}{
 		"capabilities": map[string]interface{}{
 			"textDocumentSync":           1,
 			"documentFormattingProvider": true,
+			"definitionProvider":         true,
+			"documentHighlightProvider":  true,
+			"foldingRangeProvider":       true,
+			"hoverProvider":              true,
 			"inlayHintProvider":          true,
+			"positionEncoding":           l.encoding,
+			"selectionRangeProvider":     true,
+			"documentLinkProvider": map[string]bool{
+				"resolveProvider": false,
+			},
+			"codeActionProvider": map[string][]string{
+				"codeActionKinds": {"quickfix", "refactor.extract", "refactor.inline", "refactor.rewrite"},
+			},
+			"executeCommandProvider": map[string][]string{
+				"commands": commands,
+			},
 			"completionProvider": map[string]bool{
 				"resolveProvider": true,
 			},
+			"workspace": map[string]interface{}{
+				"workspaceFolders": map[string]bool{
+					"supported":           true,
+					"changeNotifications": true,
+				},
+			},
 		},
 	}
 	response := rpc.Response{
@@ -30,6 +59,12 @@ func (l *Lsp) handleInitialize(req rpc.Request) {
 	}
 }
 
+// Registers the file watchers and starts indexing the workspace folders once the client is ready.
+func (l *Lsp) handleInitialized() {
+	l.registerWatchers()
+	l.startIndexing(l.folders)
+}
+
 func (l *Lsp) handleShutdown(req rpc.Request) {
 	response := rpc.Response{
 		Jsonrpc: "2.0",
@@ -42,3 +77,23 @@ func (l *Lsp) handleShutdown(req rpc.Request) {
 	os.Exit(0)
 }
 
+func (o InitializationOptions) remoteOptions() parser.RemoteOptions {
+	options := parser.DefaultRemoteOptions()
+	options.Offline = o.Offline
+	if o.IncludeTimeout > 0 {
+		options.Timeout = time.Duration(o.IncludeTimeout) * time.Millisecond
+	}
+	return options
+}
+
+// Returns the folders opened by the client, falling back to the deprecated root URI.
+func workspaceFolders(params InitializeParams) []string {
+	folders := make([]string, 0)
+	for _, f := range params.WorkspaceFolders {
+		folders = append(folders, pathFromURI(f.URI))
+	}
+	if len(folders) == 0 && params.RootURI != "" {
+		folders = append(folders, pathFromURI(params.RootURI))
+	}
+	return folders
+}
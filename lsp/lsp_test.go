@@ -6,8 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+	"github.com/tacsiazuma/structurizr-lsp/rpc"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -48,6 +51,17 @@ func TestRpc(t *testing.T) {
 			assert.Equal(t, testcase.Output, writer.written)
 		})
 	})
+	t.Run("initialize negotiates position encoding", func(t *testing.T) {
+		writer := &UnbufferedWriter{}
+		reader := &StringReader{}
+
+		sut := From(reader, writer, logger)
+		testcase := ParseTestFile("initialize_utf8", "successful_initialize_utf8")
+		reader.SetString(testcase.Input)
+		err := sut.Handle()
+		assert.Nil(t, err)
+		assert.Equal(t, testcase.Output, writer.written)
+	})
 	t.Run("textdocument/didOpen", func(t *testing.T) {
 		writer := &UnbufferedWriter{}
 		reader := &StringReader{}
@@ -91,6 +105,826 @@ func TestRpc(t *testing.T) {
 	})
 }
 
+func TestPositionEncoding(t *testing.T) {
+	line := "person \"😀 Name\" \"Description\""
+	t.Run("utf-32 keeps rune offsets", func(t *testing.T) {
+		assert.Equal(t, 17, UTF32.fromRunes(line, 17))
+		assert.Equal(t, 17, UTF32.toRunes(line, 17))
+	})
+	t.Run("utf-16 counts surrogate pairs as two units", func(t *testing.T) {
+		assert.Equal(t, 7, UTF16.fromRunes(line, 7))
+		assert.Equal(t, 18, UTF16.fromRunes(line, 17))
+		assert.Equal(t, 17, UTF16.toRunes(line, 18))
+	})
+	t.Run("utf-8 counts bytes", func(t *testing.T) {
+		assert.Equal(t, 20, UTF8.fromRunes(line, 17))
+		assert.Equal(t, 17, UTF8.toRunes(line, 20))
+		assert.Equal(t, len(line), UTF8.length(line))
+	})
+	t.Run("positions past the end of line are kept", func(t *testing.T) {
+		assert.Equal(t, 4, UTF16.fromRunes("ab", 4))
+		assert.Equal(t, 4, UTF16.toRunes("ab", 4))
+	})
+	t.Run("unsupported encodings fall back to utf-16", func(t *testing.T) {
+		assert.Equal(t, UTF16, negotiateEncoding([]PositionEncodingKind{"utf-7"}))
+		assert.Equal(t, UTF16, negotiateEncoding(nil))
+		assert.Equal(t, UTF8, negotiateEncoding([]PositionEncodingKind{UTF8, UTF16}))
+	})
+}
+
+func TestConversionBatch(t *testing.T) {
+	logger := initLogger()
+	path := filepath.Join(t.TempDir(), "people.dsl")
+	_ = os.WriteFile(path, []byte("person \"😀\""), 0644)
+	sut := From(&StringReader{}, &UnbufferedWriter{}, logger)
+	t.Run("files on disk are read once within a batch", func(t *testing.T) {
+		end := sut.batchConversions()
+		assert.Equal(t, Position{Line: 0, Character: 11}, sut.toPosition(parser.Location{Source: path, Line: 0, Pos: 10}))
+		_ = os.WriteFile(path, []byte("person \"a\""), 0644)
+		assert.Equal(t, Position{Line: 0, Character: 11}, sut.toPosition(parser.Location{Source: path, Line: 0, Pos: 10}))
+		end()
+	})
+	t.Run("changes on disk are read by the next batch", func(t *testing.T) {
+		assert.Equal(t, Position{Line: 0, Character: 10}, sut.toPosition(parser.Location{Source: path, Line: 0, Pos: 10}))
+		assert.Nil(t, sut.lines)
+	})
+	t.Run("open buffers are split when they change", func(t *testing.T) {
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(path), Text: "person \"a\"\nperson \"😀\""}})
+		assert.Equal(t, []string{"person \"a\"", "person \"😀\""}, sut.content[uriFromPath(path)].Lines)
+		assert.Equal(t, Position{Line: 1, Character: 11}, sut.toPosition(parser.Location{Source: path, Line: 1, Pos: 10}))
+		sut.handleDidChange(DidChangeTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(path)}, ContentChanges: []ContentChange{{Text: "person \"😀\""}}})
+		assert.Equal(t, Position{Line: 0, Character: 11}, sut.toPosition(parser.Location{Source: path, Line: 0, Pos: 10}))
+	})
+}
+
+func TestOverlayIncluder(t *testing.T) {
+	logger := initLogger()
+	dir := t.TempDir()
+	people := filepath.Join(dir, "people.dsl")
+	root := filepath.Join(dir, "workspace.dsl")
+	_ = os.WriteFile(people, []byte("person \"Disk\""), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "other.dsl"), []byte("person \"Other\""), 0644)
+	sut := From(&StringReader{}, &UnbufferedWriter{}, logger)
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(people), Text: "person \"Buffer\""}})
+	t.Run("open buffers are served before the disk", func(t *testing.T) {
+		files, err := sut.includer.Include(people)
+		assert.NoError(t, err)
+		assert.Equal(t, "person \"Buffer\"", files[0].Content)
+	})
+	t.Run("open files of included directories are served from the buffer", func(t *testing.T) {
+		files, err := sut.includer.Include(dir)
+		assert.NoError(t, err)
+		assert.Equal(t, "person \"Other\"", files[0].Content)
+		assert.Equal(t, "person \"Buffer\"", files[1].Content)
+	})
+	t.Run("analysis of the including file uses the buffer", func(t *testing.T) {
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "!include people.dsl"}})
+		content, _ := sut.getContent(uriFromPath(root))
+		assert.Equal(t, "(root  (!include (people.dsl) )(person (Buffer) ))", content.Ast.ToString())
+	})
+	t.Run("closed buffers are read from disk", func(t *testing.T) {
+		sut.handleDidClose(DidCloseTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(people)}})
+		files, err := sut.includer.Include(people)
+		assert.NoError(t, err)
+		assert.Equal(t, "person \"Disk\"", files[0].Content)
+	})
+}
+
+func TestDependents(t *testing.T) {
+	logger := initLogger()
+	dir := t.TempDir()
+	people := filepath.Join(dir, "people.dsl")
+	root := filepath.Join(dir, "workspace.dsl")
+	_ = os.WriteFile(people, []byte("person \"User\""), 0644)
+	_ = os.WriteFile(root, []byte("workspace {\nmodel {\n!include people.dsl\n}\nviews {\n}\n}"), 0644)
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\nmodel {\n!include people.dsl\n}\nviews {\n}\n}"}})
+	t.Run("included files get diagnostics of the including document", func(t *testing.T) {
+		assert.Equal(t, map[string][]string{uriFromPath(root): {}, uriFromPath(people): {}}, writer.PublishedDiagnostics())
+	})
+	t.Run("changing an included file re-analyses the including document", func(t *testing.T) {
+		writer.Reset()
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(people), Text: "person \"User\""}})
+		sut.handleDidChange(DidChangeTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(people)}, ContentChanges: []ContentChange{{Text: "person"}}})
+		assert.Equal(t, map[string][]string{uriFromPath(root): {}, uriFromPath(people): {"Expected a name for person"}}, writer.PublishedDiagnostics())
+	})
+	t.Run("closing the included file re-analyses with the content on disk", func(t *testing.T) {
+		writer.Reset()
+		sut.handleDidClose(DidCloseTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(people)}})
+		assert.Equal(t, map[string][]string{uriFromPath(root): {}, uriFromPath(people): {}}, writer.PublishedDiagnostics())
+	})
+	t.Run("saving an included file re-analyses documents which are not open", func(t *testing.T) {
+		sut.handleDidClose(DidCloseTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}})
+		_ = os.WriteFile(people, []byte("person"), 0644)
+		writer.Reset()
+		sut.handleDidSave(DidSaveTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(people)}})
+		assert.Equal(t, map[string][]string{uriFromPath(root): {}, uriFromPath(people): {"Expected a name for person"}}, writer.PublishedDiagnostics())
+	})
+}
+
+func TestWatchedFiles(t *testing.T) {
+	logger := initLogger()
+	dir := t.TempDir()
+	people := filepath.Join(dir, "people.dsl")
+	root := filepath.Join(dir, "workspace.dsl")
+	_ = os.WriteFile(people, []byte("person \"User\""), 0644)
+	_ = os.WriteFile(root, []byte("workspace {\nmodel {\n!include people.dsl\n}\nviews {\n}\n}"), 0644)
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	t.Run("included files are read from disk when the client does not watch them", func(t *testing.T) {
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\nmodel {\n!include people.dsl\n}\nviews {\n}\n}"}})
+		_ = os.WriteFile(people, []byte("person"), 0644)
+		writer.Reset()
+		sut.handleDidChange(DidChangeTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, ContentChanges: []ContentChange{{Text: "workspace {\nmodel {\n!include people.dsl\n}\nviews {\n}\n}"}}})
+		assert.Equal(t, map[string][]string{uriFromPath(root): {}, uriFromPath(people): {"Expected a name for person"}}, writer.PublishedDiagnostics())
+		sut.handleDidClose(DidCloseTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}})
+		_ = os.WriteFile(people, []byte("person \"User\""), 0644)
+	})
+	t.Run("file watchers are registered when the client supports it", func(t *testing.T) {
+		sut.watchFiles = true
+		sut.setIncluder(parser.NewIncluder())
+		writer.Reset()
+		sut.handleInitialized()
+		if assert.Equal(t, 1, len(writer.messages)) {
+			assert.Contains(t, writer.messages[0], `"method":"client/registerCapability"`)
+			assert.Contains(t, writer.messages[0], `"globPattern":"**/*.dsl"`)
+		}
+	})
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\nmodel {\n!include people.dsl\n}\nviews {\n}\n}"}})
+	sut.handleDidClose(DidCloseTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}})
+	t.Run("included files changed on disk re-analyse the including documents", func(t *testing.T) {
+		_ = os.WriteFile(people, []byte("person"), 0644)
+		writer.Reset()
+		sut.handleDidChangeWatchedFiles(DidChangeWatchedFilesParams{Changes: []FileEvent{{URI: uriFromPath(people), Type: FileChanged}}})
+		assert.Equal(t, map[string][]string{uriFromPath(root): {}, uriFromPath(people): {"Expected a name for person"}}, writer.PublishedDiagnostics())
+	})
+	t.Run("deleted included files are reported on the including document", func(t *testing.T) {
+		_ = os.Remove(people)
+		writer.Reset()
+		sut.handleDidChangeWatchedFiles(DidChangeWatchedFilesParams{Changes: []FileEvent{{URI: uriFromPath(people), Type: FileDeleted}}})
+		published := writer.PublishedDiagnostics()
+		if assert.Equal(t, 1, len(published[uriFromPath(root)])) {
+			assert.Contains(t, published[uriFromPath(root)][0], "Failed to include people.dsl")
+		}
+	})
+	t.Run("only the referenced documentation is watched", func(t *testing.T) {
+		docs := filepath.Join(dir, "docs")
+		_ = os.Mkdir(docs, 0755)
+		_ = os.WriteFile(filepath.Join(docs, "01-context.md"), []byte("## Context"), 0644)
+		writer.Reset()
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\n!docs docs\nmodel {\n}\nviews {\n}\n}"}})
+		registered := writer.messages[len(writer.messages)-1]
+		assert.Contains(t, registered, `"method":"client/registerCapability"`)
+		assert.Contains(t, registered, `"globPattern":"`+filepath.ToSlash(docs)+`/*"`)
+		assert.NotContains(t, registered, `**/*.md`)
+		writer.Reset()
+		sut.handleDidChange(DidChangeTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, ContentChanges: []ContentChange{{Text: "workspace {\nmodel {\n}\nviews {\n}\n}"}}})
+		assert.Contains(t, writer.messages[len(writer.messages)-1], `"method":"client/unregisterCapability"`)
+	})
+	t.Run("local themes are watched", func(t *testing.T) {
+		writer.Reset()
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\nmodel {\n}\nviews {\ntheme default\nthemes theme.json https://example.com/theme.json\n}\n}"}})
+		registered := writer.messages[len(writer.messages)-1]
+		assert.Contains(t, registered, `"method":"client/registerCapability"`)
+		assert.Contains(t, registered, `"globPattern":"`+filepath.ToSlash(filepath.Join(dir, "theme.json"))+`"`)
+		assert.NotContains(t, registered, `example.com`)
+		assert.NotContains(t, registered, `default`)
+	})
+	t.Run("included files outside of the workspace folders are not cached", func(t *testing.T) {
+		outside := filepath.Join(t.TempDir(), "outside.dsl")
+		_ = os.WriteFile(outside, []byte("person \"User\""), 0644)
+		_ = os.WriteFile(people, []byte("person \"User\""), 0644)
+		sut.folders, sut.watching = []string{dir}, true
+		assert.True(t, sut.watched(people))
+		assert.False(t, sut.watched(outside))
+		rel, _ := filepath.Rel(dir, outside)
+		text := "workspace {\nmodel {\n!include people.dsl\n!include \"" + filepath.ToSlash(rel) + "\"\n}\nviews {\n}\n}"
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: text}})
+		_ = os.WriteFile(outside, []byte("person"), 0644)
+		writer.Reset()
+		sut.handleDidChange(DidChangeTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, ContentChanges: []ContentChange{{Text: text}}})
+		assert.Equal(t, []string{"Expected a name for person"}, writer.PublishedDiagnostics()[uriFromPath(outside)])
+	})
+}
+
+func TestWorkspaceFolders(t *testing.T) {
+	logger := initLogger()
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first", "workspace.dsl")
+	second := filepath.Join(dir, "second", "nested", "workspace.dsl")
+	hidden := filepath.Join(dir, ".git", "workspace.dsl")
+	for _, path := range []string{first, second, hidden} {
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		_ = os.WriteFile(path, []byte("workspace {\nmodel {\n}\nviews {\n}\n}"), 0644)
+	}
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	t.Run("workspace files are discovered outside of hidden directories", func(t *testing.T) {
+		assert.Equal(t, []string{first, second}, discoverWorkspaces([]string{dir}))
+	})
+	t.Run("folders are read from the initialize params", func(t *testing.T) {
+		assert.Equal(t, []string{dir}, workspaceFolders(InitializeParams{RootURI: uriFromPath(dir)}))
+		assert.Equal(t, []string{first}, workspaceFolders(InitializeParams{RootURI: uriFromPath(dir), WorkspaceFolders: []WorkspaceFolder{{URI: uriFromPath(first)}}}))
+	})
+	t.Run("indexing analyses the discovered workspaces and reports progress", func(t *testing.T) {
+		sut.folders = []string{dir}
+		sut.indexFolders(sut.folders, "token")
+		assert.Equal(t, map[string][]string{uriFromPath(first): {}, uriFromPath(second): {}}, writer.PublishedDiagnostics())
+		assert.Contains(t, writer.messages[0], `"kind":"begin"`)
+		assert.Contains(t, writer.messages[len(writer.messages)-1], `"kind":"end"`)
+		assert.Contains(t, sut.analyses, first)
+		assert.Contains(t, sut.analyses, second)
+	})
+	t.Run("removed folders are forgotten", func(t *testing.T) {
+		writer.Reset()
+		sut.handleDidChangeWorkspaceFolders(DidChangeWorkspaceFoldersParams{Event: WorkspaceFoldersChangeEvent{Removed: []WorkspaceFolder{{URI: uriFromPath(dir)}}}})
+		assert.Empty(t, sut.folders)
+		assert.Empty(t, sut.analyses)
+		assert.Equal(t, map[string][]string{uriFromPath(first): {}, uriFromPath(second): {}}, writer.PublishedDiagnostics())
+	})
+}
+
+func TestDefinition(t *testing.T) {
+	logger := initLogger()
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.dsl")
+	people := filepath.Join(dir, "people.dsl")
+	root := filepath.Join(dir, "workspace.dsl")
+	_ = os.WriteFile(base, []byte("workspace {\nmodel {\nuser = person \"User\"\n}\nviews {\n}\n}"), 0644)
+	_ = os.WriteFile(people, []byte("user -> ss"), 0644)
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace extends base.dsl {\nmodel {\nss = softwareSystem \"System\"\n!include people.dsl\n}\n}"}})
+	definition := func(uri string, position Position) []Location {
+		writer.Reset()
+		sut.handleDefinition(1, TextDocumentPositionParams{TextDocument: TextDocumentItem{URI: uri}, Position: position})
+		var response struct {
+			Result []Location `json:"result"`
+		}
+		decodeResult(t, writer, &response)
+		return response.Result
+	}
+	t.Run("inherited elements are defined in the base workspace", func(t *testing.T) {
+		assert.Equal(t, []Location{{URI: uriFromPath(base), Range: Range{Start: Position{Line: 2, Character: 0}, End: Position{Line: 2, Character: 4}}}}, definition(uriFromPath(people), Position{Line: 0, Character: 2}))
+	})
+	t.Run("references in included files resolve through the including document", func(t *testing.T) {
+		assert.Equal(t, []Location{{URI: uriFromPath(root), Range: Range{Start: Position{Line: 2, Character: 0}, End: Position{Line: 2, Character: 2}}}}, definition(uriFromPath(people), Position{Line: 0, Character: 9}))
+	})
+	t.Run("no definition outside of identifiers", func(t *testing.T) {
+		assert.Empty(t, definition(uriFromPath(root), Position{Line: 2, Character: 8}))
+	})
+}
+
+func TestCompletion(t *testing.T) {
+	logger := initLogger()
+	root := filepath.Join(t.TempDir(), "workspace.dsl")
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\nmodel {\narchetypes {\napplication = container\n}\nss = softwareSystem \"System\"\n}\n}"}})
+	writer.Reset()
+	sut.handleCompletion(1, TextDocumentPositionParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, Position: Position{Line: 6, Character: 0}})
+	var response struct {
+		Result []CompletionItem `json:"result"`
+	}
+	decodeResult(t, writer, &response)
+	assert.Contains(t, response.Result, CompletionItem{Label: "container", Kind: KeywordCompletion})
+	assert.Contains(t, response.Result, CompletionItem{Label: "application", Kind: ClassCompletion, Detail: "archetype of container"})
+	assert.Contains(t, response.Result, CompletionItem{Label: "ss", Kind: VariableCompletion, Detail: "softwareSystem System"})
+}
+
+func TestHover(t *testing.T) {
+	logger := initLogger()
+	root := filepath.Join(t.TempDir(), "workspace.dsl")
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\nmodel {\narchetypes {\napplication = container {\ntechnology \"Java\"\n}\n}\nss = softwareSystem \"System\" {\napi = application \"API\" \"Serves requests\"\n}\n}\n}"}})
+	hover := func(position Position) *Hover {
+		writer.Reset()
+		sut.handleHover(1, TextDocumentPositionParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, Position: position})
+		var response struct {
+			Result *Hover `json:"result"`
+		}
+		decodeResult(t, writer, &response)
+		return response.Result
+	}
+	t.Run("elements are described", func(t *testing.T) {
+		result := hover(Position{Line: 8, Character: 1})
+		if assert.NotNil(t, result) {
+			assert.Equal(t, "markdown", result.Contents.Kind)
+			assert.Equal(t, "**API** `container`\n\nServes requests\n\nTechnology: Java\n\nTags: Element, Container", result.Contents.Value)
+			assert.Equal(t, Range{Start: Position{Line: 8, Character: 0}, End: Position{Line: 8, Character: 3}}, result.Range)
+		}
+	})
+	t.Run("archetypes are described", func(t *testing.T) {
+		result := hover(Position{Line: 8, Character: 8})
+		if assert.NotNil(t, result) {
+			assert.Equal(t, "**application** archetype of `container`\n\nTechnology: Java", result.Contents.Value)
+		}
+	})
+	t.Run("urls, properties and perspectives are described", func(t *testing.T) {
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\nmodel {\nuser = person \"User\" {\nurl \"https://example.com\"\nproperties {\n\"owner\" \"Sales\"\n}\nperspectives {\n\"Security\" \"Authenticated\"\n}\n}\n}\n}"}})
+		result := hover(Position{Line: 2, Character: 1})
+		if assert.NotNil(t, result) {
+			assert.Equal(t, "**User** `person`\n\nTags: Element, Person\n\nURL: https://example.com\n\nProperties:\n\n- owner: Sales\n\nPerspectives:\n\n- Security: Authenticated", result.Contents.Value)
+		}
+	})
+	t.Run("nothing is described outside of references", func(t *testing.T) {
+		assert.Nil(t, hover(Position{Line: 0, Character: 2}))
+	})
+}
+
+func TestDocumentLink(t *testing.T) {
+	logger := initLogger()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "workspace.dsl")
+	_ = os.WriteFile(filepath.Join(dir, "people.dsl"), []byte("user = person \"User\""), 0644)
+	_ = os.MkdirAll(filepath.Join(dir, "systems"), 0755)
+	_ = os.WriteFile(filepath.Join(dir, "build.groovy"), []byte(""), 0644)
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\n!docs docs\nmodel {\n!include people.dsl\n!include \"systems\"\nss = softwareSystem \"System\" {\nurl https://example.com\n}\n}\n!script build.groovy\n!script groovy {\n}\nviews {\ntheme default\nthemes https://example.com/theme.json\n}\n}"}})
+	writer.Reset()
+	sut.handleDocumentLink(1, DocumentLinkParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}})
+	var response struct {
+		Result []DocumentLink `json:"result"`
+	}
+	decodeResult(t, writer, &response)
+	link := func(line, start, end int, target string) DocumentLink {
+		return DocumentLink{Range: Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}, Target: target}
+	}
+	assert.Equal(t, []DocumentLink{
+		link(1, 6, 10, uriFromPath(filepath.Join(dir, "docs"))),
+		link(3, 9, 19, uriFromPath(filepath.Join(dir, "people.dsl"))),
+		link(4, 10, 17, uriFromPath(filepath.Join(dir, "systems"))),
+		link(6, 4, 23, "https://example.com"),
+		link(9, 8, 20, uriFromPath(filepath.Join(dir, "build.groovy"))),
+		link(14, 7, 37, "https://example.com/theme.json"),
+	}, response.Result)
+}
+
+func TestFoldingRange(t *testing.T) {
+	logger := initLogger()
+	root := filepath.Join(t.TempDir(), "workspace.dsl")
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "/*\n * The workspace\n */\nworkspace {\nmodel {\n!include a.dsl\n!include b.dsl\n// first\n// second\nss = softwareSystem \"System\" {\nproperties {\n\"a\" \"b\"\n}\n}\n}\n}"}})
+	writer.Reset()
+	sut.handleFoldingRange(1, FoldingRangeParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}})
+	var response struct {
+		Result []FoldingRange `json:"result"`
+	}
+	decodeResult(t, writer, &response)
+	assert.Equal(t, []FoldingRange{
+		{StartLine: 0, EndLine: 2, Kind: "comment"},
+		{StartLine: 3, EndLine: 14, Kind: "region"},
+		{StartLine: 4, EndLine: 13, Kind: "region"},
+		{StartLine: 5, EndLine: 6, Kind: "region"},
+		{StartLine: 7, EndLine: 8, Kind: "comment"},
+		{StartLine: 9, EndLine: 12, Kind: "region"},
+		{StartLine: 10, EndLine: 11, Kind: "region"},
+	}, response.Result)
+}
+
+func TestCodeAction(t *testing.T) {
+	logger := initLogger()
+	root := filepath.Join(t.TempDir(), "workspace.dsl")
+	writer := &UnbufferedWriter{}
+	reader := &StringReader{}
+	sut := From(reader, writer, logger)
+	actions := func(text string, diag Diagnostic) []CodeAction {
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: text}})
+		writer.Reset()
+		sut.handleCodeAction(1, CodeActionParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, Context: CodeActionContext{Diagnostics: []Diagnostic{diag}, Only: []string{"quickfix"}}})
+		var response struct {
+			Result []CodeAction `json:"result"`
+		}
+		decodeResult(t, writer, &response)
+		return response.Result
+	}
+	at := func(line, character int) Range {
+		return Range{Start: Position{Line: line, Character: character}, End: Position{Line: line, Character: character}}
+	}
+	ours := func(message string, code parser.DiagnosticCode, r Range, data ...string) Diagnostic {
+		return *newDiagnostic(&parser.Diagnostic{Message: message, Code: code, Data: data}, r)
+	}
+	edit := func(t *testing.T, result []CodeAction, title string, expected TextEdit) {
+		if assert.Equal(t, 1, len(result)) {
+			assert.Equal(t, title, result[0].Title)
+			assert.Equal(t, "quickfix", result[0].Kind)
+			assert.Equal(t, []TextEdit{expected}, result[0].Edit.Changes[uriFromPath(root)])
+		}
+	}
+	t.Run("missing model and views are added", func(t *testing.T) {
+		result := actions("workspace {\n}", ours("Workspace must contain a model", parser.CodeMissingModel, at(0, 0)))
+		edit(t, result, "Add model block", TextEdit{Range: at(1, 0), NewText: "    model {\n    }\n"})
+		result = actions("workspace {\n}", ours("Workspace must contain views", parser.CodeMissingViews, at(0, 0)))
+		edit(t, result, "Add views block", TextEdit{Range: at(1, 0), NewText: "    views {\n    }\n"})
+	})
+	t.Run("opening braces are moved to the previous line", func(t *testing.T) {
+		result := actions("workspace {\nmodel\n{\n}\nviews {\n}\n}", ours("Opening curly brace symbols ({) must be on the same line.", parser.CodeOpeningBrace, at(2, 0)))
+		edit(t, result, "Move { to the end of the previous line", TextEdit{Range: Range{Start: Position{Line: 1, Character: 5}, End: Position{Line: 2, Character: 1}}, NewText: " {"})
+	})
+	t.Run("closing braces are moved to a line of their own", func(t *testing.T) {
+		result := actions("workspace {\n    model {\n        ss = softwareSystem \"System\" }\n    views {\n    }\n}", ours("Closing curly brace symbols (}) must be on a line of their own.", parser.CodeClosingBrace, at(2, 37)))
+		edit(t, result, "Move } to a line of its own", TextEdit{Range: Range{Start: Position{Line: 2, Character: 36}, End: Position{Line: 2, Character: 37}}, NewText: "\n    "})
+	})
+	t.Run("invalid options are replaced by the closest value", func(t *testing.T) {
+		result := actions("workspace {\n!identifiers hierarchcal\nmodel {\n}\nviews {\n}\n}", ours("Invalid option, possible values [flat hierarchical]", parser.CodeInvalidOption, at(1, 0), "flat", "hierarchical"))
+		edit(t, result, "Replace with hierarchical", TextEdit{Range: Range{Start: Position{Line: 1, Character: 13}, End: Position{Line: 1, Character: 24}}, NewText: "hierarchical"})
+	})
+	t.Run("missing elements are created", func(t *testing.T) {
+		result := actions("workspace {\n    model {\n        user = person \"User\"\n        user -> missing\n    }\n    views {\n    }\n}", ours("Unknown identifier missing", parser.CodeUnknownIdentifier, at(3, 16), "missing"))
+		edit(t, result, "Create software system missing", TextEdit{Range: at(3, 0), NewText: "        missing = softwareSystem \"missing\"\n"})
+		result = actions("workspace {\n    model {\n    }\n    views {\n        systemContext missing {\n        }\n    }\n}", ours("Unknown identifier missing", parser.CodeUnknownIdentifier, at(4, 22), "missing"))
+		edit(t, result, "Create software system missing", TextEdit{Range: at(2, 0), NewText: "        missing = softwareSystem \"missing\"\n"})
+	})
+	t.Run("fixes depend on the code of the diagnostic instead of its message", func(t *testing.T) {
+		result := actions("workspace {\n}", ours("A model is required", parser.CodeMissingModel, at(0, 0)))
+		edit(t, result, "Add model block", TextEdit{Range: at(1, 0), NewText: "    model {\n    }\n"})
+	})
+	t.Run("diagnostics of other sources are skipped", func(t *testing.T) {
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\n}"}})
+		writer.Reset()
+		own, _ := json.Marshal(ours("Workspace must contain views", parser.CodeMissingViews, at(0, 0)))
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"textDocument/codeAction","params":{"textDocument":{"uri":%q},"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"context":{"diagnostics":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":0}},"message":"Workspace must contain a model","source":"other","code":42,"data":{"fix":true}},%s],"only":["quickfix"]}}}`, uriFromPath(root), own)
+		reader.SetString(fmt.Sprintf("Content-Length: %d\n\n%s", len(body), body))
+		assert.NoError(t, sut.Handle())
+		var response struct {
+			ID     int          `json:"id"`
+			Result []CodeAction `json:"result"`
+		}
+		decodeResult(t, writer, &response)
+		assert.Equal(t, 2, response.ID)
+		edit(t, response.Result, "Add views block", TextEdit{Range: at(1, 0), NewText: "    views {\n    }\n"})
+	})
+	t.Run("unreadable requests are answered without actions", func(t *testing.T) {
+		writer.Reset()
+		body := `{"jsonrpc":"2.0","id":3,"method":"textDocument/codeAction","params":{"context":{"diagnostics":"none"}}}`
+		reader.SetString(fmt.Sprintf("Content-Length: %d\n\n%s", len(body), body))
+		assert.Error(t, sut.Handle())
+		var response struct {
+			ID     int          `json:"id"`
+			Result []CodeAction `json:"result"`
+		}
+		decodeResult(t, writer, &response)
+		assert.Equal(t, 3, response.ID)
+		assert.Equal(t, []CodeAction{}, response.Result)
+	})
+	t.Run("other diagnostics have no fixes", func(t *testing.T) {
+		assert.Empty(t, actions("workspace {\n}", Diagnostic{Message: "Duplicate archetype", Range: at(0, 0)}))
+		assert.Empty(t, actions("workspace {\n}", Diagnostic{Message: "Workspace must contain a model", Range: at(0, 0)}))
+	})
+}
+
+func TestExtractInclude(t *testing.T) {
+	logger := initLogger()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "workspace.dsl")
+	_ = os.WriteFile(filepath.Join(dir, "ss.dsl"), []byte(""), 0644)
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	sut.workspaceEdit = WorkspaceEditClientCapabilities{DocumentChanges: true, ResourceOperations: []string{"create"}}
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\n    model {\n        ss = softwareSystem \"System\" {\n            api = container \"API\"\n            db = container \"Database\"\n        }\n    }\n    views {\n    }\n}"}})
+	actions := func(rng Range) []CodeAction {
+		writer.Reset()
+		sut.handleCodeAction(1, CodeActionParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, Range: rng})
+		var response struct {
+			Result []CodeAction `json:"result"`
+		}
+		decodeResult(t, writer, &response)
+		return response.Result
+	}
+	containers := Range{Start: Position{Line: 3, Character: 0}, End: Position{Line: 5, Character: 0}}
+	t.Run("whole statements of the model can be extracted", func(t *testing.T) {
+		result := actions(containers)
+		if assert.Equal(t, 1, len(result)) {
+			assert.Equal(t, "refactor.extract", result[0].Kind)
+			assert.Equal(t, "structurizr.extractInclude", result[0].Command.Command)
+		}
+	})
+	t.Run("parts of statements cannot be extracted", func(t *testing.T) {
+		assert.Empty(t, actions(Range{Start: Position{Line: 2, Character: 0}, End: Position{Line: 3, Character: 10}}))
+		assert.Empty(t, actions(Range{Start: Position{Line: 7, Character: 0}, End: Position{Line: 8, Character: 0}}))
+	})
+	t.Run("clients unable to create files are not offered the extraction", func(t *testing.T) {
+		sut.workspaceEdit.ResourceOperations = nil
+		defer func() { sut.workspaceEdit.ResourceOperations = []string{"create"} }()
+		assert.Empty(t, actions(containers))
+	})
+	args, _ := json.Marshal(RefactoringArguments{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, Range: containers})
+	t.Run("the statements are moved into a new file", func(t *testing.T) {
+		writer.Reset()
+		sut.handleExecuteCommand(1, ExecuteCommandParams{Command: "structurizr.extractInclude", Arguments: []json.RawMessage{args}})
+		if !assert.Equal(t, 1, len(writer.messages)) {
+			return
+		}
+		var request struct {
+			Method string `json:"method"`
+			Params struct {
+				Edit struct {
+					DocumentChanges []json.RawMessage `json:"documentChanges"`
+				} `json:"edit"`
+			} `json:"params"`
+		}
+		decodeMessage(t, writer.messages[0], &request)
+		assert.Equal(t, "workspace/applyEdit", request.Method)
+		changes := request.Params.Edit.DocumentChanges
+		if !assert.Equal(t, 3, len(changes)) {
+			return
+		}
+		var create CreateFile
+		assert.NoError(t, json.Unmarshal(changes[0], &create))
+		assert.Equal(t, CreateFile{Kind: "create", URI: uriFromPath(filepath.Join(dir, "ss-2.dsl"))}, create)
+		var content, include TextDocumentEdit
+		assert.NoError(t, json.Unmarshal(changes[1], &content))
+		assert.NoError(t, json.Unmarshal(changes[2], &include))
+		assert.Equal(t, []TextEdit{{NewText: "api = container \"API\"\ndb = container \"Database\"\n"}}, content.Edits)
+		assert.Equal(t, uriFromPath(root), include.TextDocument.URI)
+		assert.Equal(t, []TextEdit{{Range: Range{Start: Position{Line: 3, Character: 0}, End: Position{Line: 5, Character: 0}}, NewText: "            !include ss-2.dsl\n"}}, include.Edits)
+		writer.Reset()
+		sut.handleResponse(clientResponse{ID: sut.requestID, Result: json.RawMessage(`{"applied":true}`)})
+		assert.Equal(t, `{"jsonrpc":"2.0","id":1}`, writer.written[strings.Index(writer.written, "\r\n\r\n")+4:])
+	})
+	t.Run("edits rejected by the client fail the command", func(t *testing.T) {
+		sut.handleExecuteCommand(2, ExecuteCommandParams{Command: "structurizr.extractInclude", Arguments: []json.RawMessage{args}})
+		writer.Reset()
+		sut.handleResponse(clientResponse{ID: sut.requestID, Result: json.RawMessage(`{"applied":false,"failureReason":"file exists"}`)})
+		var response struct {
+			Error rpc.Error `json:"error"`
+		}
+		decodeResult(t, writer, &response)
+		assert.Equal(t, rpc.Error{Code: -32803, Message: "Failed to apply 'Extract to !include file': file exists"}, response.Error)
+	})
+	t.Run("unknown commands are rejected", func(t *testing.T) {
+		writer.Reset()
+		sut.handleExecuteCommand(1, ExecuteCommandParams{Command: "unknown", Arguments: []json.RawMessage{[]byte("{}")}})
+		assert.Contains(t, writer.written, "Unknown command unknown")
+	})
+}
+
+func TestInlineInclude(t *testing.T) {
+	logger := initLogger()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "workspace.dsl")
+	other := filepath.Join(dir, "other.dsl")
+	_ = os.MkdirAll(filepath.Join(dir, "domain", "parts"), 0755)
+	_ = os.WriteFile(filepath.Join(dir, "people.dsl"), []byte("user = person \"User\"\n"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "domain", "a.dsl"), []byte("a = softwareSystem \"A\" {\n    !include parts/api.dsl\n}\n"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "domain", "b.dsl"), []byte("b = softwareSystem \"B\"\n"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "domain", "parts", "api.dsl"), []byte("api = container \"API\"\n"), 0644)
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	sut.workspaceEdit = WorkspaceEditClientCapabilities{DocumentChanges: true, ResourceOperations: []string{"delete"}}
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(other), Text: "workspace {\nmodel {\n!include people.dsl\n}\nviews {\n}\n}"}})
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\n    model {\n        !include people.dsl\n        !include domain\n    }\n    views {\n    }\n}"}})
+	line := func(n int) Range {
+		return Range{Start: Position{Line: n, Character: 10}, End: Position{Line: n, Character: 10}}
+	}
+	titles := func(n int) []string {
+		writer.Reset()
+		sut.handleCodeAction(1, CodeActionParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, Range: line(n)})
+		var response struct {
+			Result []CodeAction `json:"result"`
+		}
+		decodeResult(t, writer, &response)
+		titles := make([]string, 0)
+		for _, a := range response.Result {
+			titles = append(titles, a.Title)
+		}
+		return titles
+	}
+	t.Run("files included elsewhere are kept", func(t *testing.T) {
+		assert.Equal(t, []string{"Inline !include"}, titles(2))
+	})
+	t.Run("files included only by the directive may be deleted", func(t *testing.T) {
+		assert.Equal(t, []string{"Inline !include", "Inline !include and delete the included files"}, titles(3))
+	})
+	t.Run("other lines cannot be inlined", func(t *testing.T) {
+		assert.Empty(t, titles(1))
+	})
+	t.Run("files are not offered to be deleted when the client cannot delete them", func(t *testing.T) {
+		sut.workspaceEdit.ResourceOperations = nil
+		defer func() { sut.workspaceEdit.ResourceOperations = []string{"delete"} }()
+		assert.Equal(t, []string{"Inline !include"}, titles(3))
+	})
+	t.Run("directories are inlined file by file", func(t *testing.T) {
+		args, _ := json.Marshal(RefactoringArguments{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, Range: line(3), Delete: true})
+		writer.Reset()
+		sut.handleExecuteCommand(1, ExecuteCommandParams{Command: "structurizr.inlineInclude", Arguments: []json.RawMessage{args}})
+		if !assert.Equal(t, 1, len(writer.messages)) {
+			return
+		}
+		var request struct {
+			Params struct {
+				Edit struct {
+					DocumentChanges []json.RawMessage `json:"documentChanges"`
+				} `json:"edit"`
+			} `json:"params"`
+		}
+		decodeMessage(t, writer.messages[0], &request)
+		changes := request.Params.Edit.DocumentChanges
+		if !assert.Equal(t, 3, len(changes)) {
+			return
+		}
+		var inlined TextDocumentEdit
+		assert.NoError(t, json.Unmarshal(changes[0], &inlined))
+		assert.Equal(t, []TextEdit{{
+			Range:   Range{Start: Position{Line: 3, Character: 0}, End: Position{Line: 4, Character: 0}},
+			NewText: "        a = softwareSystem \"A\" {\n            !include domain/parts/api.dsl\n        }\n        b = softwareSystem \"B\"\n",
+		}}, inlined.Edits)
+		deleted := make([]DeleteFile, 2)
+		assert.NoError(t, json.Unmarshal(changes[1], &deleted[0]))
+		assert.NoError(t, json.Unmarshal(changes[2], &deleted[1]))
+		assert.Equal(t, []DeleteFile{
+			{Kind: "delete", URI: uriFromPath(filepath.Join(dir, "domain", "a.dsl"))},
+			{Kind: "delete", URI: uriFromPath(filepath.Join(dir, "domain", "b.dsl"))},
+		}, deleted)
+	})
+	t.Run("paths relative to the inlined files are rebased", func(t *testing.T) {
+		from, to := filepath.Join(dir, "domain"), dir
+		for line, expected := range map[string]string{
+			"!include include":                "!include domain/include",
+			"    !docs \"docs\" com.Importer": "    !docs \"domain/docs\" com.Importer",
+			"!adrs adrs":                      "!adrs domain/adrs",
+			"!script script.groovy":           "!script domain/script.groovy",
+			"!script groovy {":                "!script groovy {",
+			"themes default theme.json":       "themes default domain/theme.json",
+			"theme https://example.com/t":     "theme https://example.com/t",
+			"image diagram.png":               "image domain/diagram.png",
+			"image * \"Images\" {":            "image * \"Images\" {",
+			"!include   parts  # people":      "!include   domain/parts  # people",
+			"!script scripts/build":           "!script domain/scripts/build",
+			"theme \"my theme.json\"":         "theme \"domain/my theme.json\"",
+			"image \"a b.png\"":               "image \"domain/a b.png\"",
+			"theme default":                   "theme default",
+		} {
+			assert.Equal(t, expected, rebasePaths(line, from, to))
+		}
+		assert.Equal(t, "!include \"my domain/include\"", rebasePaths("!include include", filepath.Join(dir, "my domain"), dir))
+	})
+}
+
+func TestConvertIdentifiers(t *testing.T) {
+	logger := initLogger()
+	root := filepath.Join(t.TempDir(), "workspace.dsl")
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	convert := func(text string) []TextEdit {
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: text}})
+		args, _ := json.Marshal(RefactoringArguments{TextDocument: TextDocumentItem{URI: uriFromPath(root)}})
+		writer.Reset()
+		sut.handleExecuteCommand(1, ExecuteCommandParams{Command: "structurizr.convertIdentifiers", Arguments: []json.RawMessage{args}})
+		var request struct {
+			Params ApplyWorkspaceEditParams `json:"params"`
+		}
+		decodeMessage(t, writer.messages[0], &request)
+		return request.Params.Edit.Changes[uriFromPath(root)]
+	}
+	edit := func(line, start, end int, text string) TextEdit {
+		return TextEdit{Range: Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}, NewText: text}
+	}
+	t.Run("the conversion is offered on the workspace", func(t *testing.T) {
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\nmodel {\n}\nviews {\n}\n}"}})
+		writer.Reset()
+		sut.handleCodeAction(1, CodeActionParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, Context: CodeActionContext{Only: []string{"refactor"}}})
+		var response struct {
+			Result []CodeAction `json:"result"`
+		}
+		decodeResult(t, writer, &response)
+		if assert.Equal(t, 1, len(response.Result)) {
+			assert.Equal(t, "Convert to hierarchical identifiers", response.Result[0].Title)
+		}
+	})
+	t.Run("references are qualified in hierarchical workspaces", func(t *testing.T) {
+		edits := convert("workspace {\n    model {\n        user = person \"User\"\n        ss = softwareSystem \"System\" {\n            api = container \"API\"\n            db = container \"Database\"\n            api -> db \"Reads\"\n        }\n        user -> api \"Uses\"\n    }\n    views {\n        container ss {\n            include api\n        }\n    }\n}")
+		assert.ElementsMatch(t, []TextEdit{
+			edit(6, 12, 15, "ss.api"),
+			edit(6, 19, 21, "ss.db"),
+			edit(8, 16, 19, "ss.api"),
+			edit(12, 20, 23, "ss.api"),
+			edit(1, 0, 0, "    !identifiers hierarchical\n"),
+		}, edits)
+	})
+	t.Run("references are unqualified in flat workspaces", func(t *testing.T) {
+		edits := convert("workspace {\n    !identifiers hierarchical\n    model {\n        user = person \"User\"\n        ss = softwareSystem \"System\" {\n            api = container \"API\"\n        }\n        user -> ss.api \"Uses\"\n    }\n    views {\n    }\n}")
+		assert.ElementsMatch(t, []TextEdit{
+			edit(7, 16, 22, "api"),
+			edit(1, 17, 29, "flat"),
+		}, edits)
+	})
+	t.Run("colliding flat identifiers are reported", func(t *testing.T) {
+		sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\n!identifiers hierarchical\nmodel {\na = softwareSystem \"A\" {\napi = container \"API\"\n}\nb = softwareSystem \"B\" {\napi = container \"API\"\n}\n}\nviews {\n}\n}"}})
+		args, _ := json.Marshal(RefactoringArguments{TextDocument: TextDocumentItem{URI: uriFromPath(root)}})
+		writer.Reset()
+		sut.handleExecuteCommand(1, ExecuteCommandParams{Command: "structurizr.convertIdentifiers", Arguments: []json.RawMessage{args}})
+		if assert.Equal(t, 1, len(writer.messages)) {
+			assert.Contains(t, writer.written, "Cannot convert to flat identifiers as they would collide: api (a.api, b.api)")
+		}
+	})
+}
+
+func TestDocumentHighlight(t *testing.T) {
+	logger := initLogger()
+	root := filepath.Join(t.TempDir(), "workspace.dsl")
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\nmodel {\nuser = person \"User\"\nss = softwareSystem \"System\"\nuser -> ss \"Uses\"\n}\nviews {\nsystemContext ss {\ninclude user\n}\ndynamic * {\nuser -> ss \"Requests\"\n}\n}\n}"}})
+	highlights := func(position Position) []DocumentHighlight {
+		writer.Reset()
+		sut.handleDocumentHighlight(1, TextDocumentPositionParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, Position: position})
+		var response struct {
+			Result []DocumentHighlight `json:"result"`
+		}
+		decodeResult(t, writer, &response)
+		return response.Result
+	}
+	t.Run("the definition is written and the usages are read", func(t *testing.T) {
+		assert.Equal(t, []DocumentHighlight{
+			{Range: Range{Start: Position{Line: 3, Character: 0}, End: Position{Line: 3, Character: 2}}, Kind: WriteHighlight},
+			{Range: Range{Start: Position{Line: 4, Character: 8}, End: Position{Line: 4, Character: 10}}, Kind: ReadHighlight},
+			{Range: Range{Start: Position{Line: 7, Character: 14}, End: Position{Line: 7, Character: 16}}, Kind: ReadHighlight},
+			{Range: Range{Start: Position{Line: 11, Character: 8}, End: Position{Line: 11, Character: 10}}, Kind: ReadHighlight},
+		}, highlights(Position{Line: 4, Character: 9}))
+	})
+	t.Run("nothing is highlighted outside of identifiers", func(t *testing.T) {
+		assert.Empty(t, highlights(Position{Line: 2, Character: 10}))
+	})
+}
+
+func TestSelectionRange(t *testing.T) {
+	logger := initLogger()
+	root := filepath.Join(t.TempDir(), "workspace.dsl")
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\nmodel {\nss = softwareSystem \"System\" {\napi = container \"API\" \"Serves\" \"Go\"\n}\n}\n}"}})
+	writer.Reset()
+	sut.handleSelectionRange(1, SelectionRangeParams{TextDocument: TextDocumentItem{URI: uriFromPath(root)}, Positions: []Position{{Line: 3, Character: 18}, {Line: 7, Character: 0}}})
+	var response struct {
+		Result []*SelectionRange `json:"result"`
+	}
+	decodeResult(t, writer, &response)
+	if !assert.Equal(t, 2, len(response.Result)) {
+		return
+	}
+	ranges := make([]Range, 0)
+	for s := response.Result[0]; s != nil; s = s.Parent {
+		ranges = append(ranges, s.Range)
+	}
+	assert.Equal(t, []Range{
+		{Start: Position{Line: 3, Character: 16}, End: Position{Line: 3, Character: 21}},
+		{Start: Position{Line: 3, Character: 6}, End: Position{Line: 3, Character: 35}},
+		{Start: Position{Line: 3, Character: 0}, End: Position{Line: 3, Character: 35}},
+		{Start: Position{Line: 2, Character: 5}, End: Position{Line: 4, Character: 1}},
+		{Start: Position{Line: 2, Character: 0}, End: Position{Line: 4, Character: 1}},
+		{Start: Position{Line: 1, Character: 0}, End: Position{Line: 5, Character: 1}},
+		{Start: Position{Line: 0, Character: 0}, End: Position{Line: 6, Character: 1}},
+	}, ranges)
+	assert.Equal(t, &SelectionRange{Range: Range{Start: Position{Line: 7, Character: 0}, End: Position{Line: 7, Character: 0}}}, response.Result[1])
+}
+
+func TestViewContents(t *testing.T) {
+	logger := initLogger()
+	root := filepath.Join(t.TempDir(), "workspace.dsl")
+	writer := &UnbufferedWriter{}
+	sut := From(&StringReader{}, writer, logger)
+	sut.handleDidOpen(DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uriFromPath(root), Text: "workspace {\nmodel {\nuser = person \"User\"\nss = softwareSystem \"System\"\nuser -> ss \"Uses\"\n}\nviews {\nsystemContext ss \"context\" {\ninclude *\n}\n}\n}"}})
+	contents := func(params ViewContentsParams) *ViewContents {
+		writer.Reset()
+		params.TextDocument = TextDocumentItem{URI: uriFromPath(root)}
+		sut.handleViewContents(1, params)
+		var response struct {
+			Result *ViewContents `json:"result"`
+		}
+		decodeResult(t, writer, &response)
+		return response.Result
+	}
+	t.Run("views are selected by key", func(t *testing.T) {
+		result := contents(ViewContentsParams{Key: "context"})
+		if assert.NotNil(t, result) {
+			assert.Equal(t, "systemContext", result.Type)
+			assert.Equal(t, []ViewElement{
+				{Identifier: "ss", Name: "System", Type: "softwareSystem", Tags: []string{"Element", "Software System"}, Location: Location{URI: uriFromPath(root), Range: Range{Start: Position{Line: 3, Character: 0}, End: Position{Line: 3, Character: 2}}}},
+				{Identifier: "user", Name: "User", Type: "person", Tags: []string{"Element", "Person"}, Location: Location{URI: uriFromPath(root), Range: Range{Start: Position{Line: 2, Character: 0}, End: Position{Line: 2, Character: 4}}}},
+			}, result.Elements)
+			if assert.Equal(t, 1, len(result.Relationships)) {
+				assert.Equal(t, 1, result.Relationships[0].Source)
+				assert.Equal(t, 0, result.Relationships[0].Destination)
+				assert.Equal(t, "Uses", result.Relationships[0].Description)
+			}
+		}
+	})
+	t.Run("views are selected by position", func(t *testing.T) {
+		result := contents(ViewContentsParams{Position: &Position{Line: 8, Character: 3}})
+		if assert.NotNil(t, result) {
+			assert.Equal(t, "context", result.Key)
+		}
+	})
+	t.Run("unknown views result in null", func(t *testing.T) {
+		assert.Nil(t, contents(ViewContentsParams{Key: "missing"}))
+	})
+}
+
 func LoadFile(reader *StringReader, writer *UnbufferedWriter, sut *Lsp) {
 	c := ParseTestFile("openfile_for_inlay_hints", "publish_diagnostics")
 	reader.SetString(c.Input)
@@ -103,7 +937,8 @@ func LoadFile(reader *StringReader, writer *UnbufferedWriter, sut *Lsp) {
 
 // UnbufferedWriter writes data directly to an underlying destination.
 type UnbufferedWriter struct {
-	written string
+	written  string
+	messages []string
 }
 
 // StringReader is a custom reader that allows setting a string later.
@@ -128,11 +963,49 @@ func (sr *StringReader) Read(p []byte) (int, error) {
 // Write implements the io.Writer interface.
 func (w *UnbufferedWriter) Write(p []byte) (int, error) {
 	w.written = string(p)
+	w.messages = append(w.messages, string(p))
 	return len(p), nil
 }
 
 func (w *UnbufferedWriter) Reset() {
 	w.written = ""
+	w.messages = nil
+}
+
+// Decodes the last written message into v, failing the test when it is not valid JSON.
+func decodeResult(t *testing.T, w *UnbufferedWriter, v interface{}) {
+	t.Helper()
+	decodeMessage(t, w.written, v)
+}
+
+// Decodes the body of a message into v, failing the test when it is not valid JSON.
+func decodeMessage(t *testing.T, message string, v interface{}) {
+	t.Helper()
+	body := message[strings.Index(message, "\r\n\r\n")+4:]
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		t.Fatalf("failed to decode %s: %s", body, err)
+	}
+}
+
+// PublishedDiagnostics collects the messages of the diagnostics published since the last reset by URI.
+func (w *UnbufferedWriter) PublishedDiagnostics() map[string][]string {
+	published := make(map[string][]string)
+	for _, m := range w.messages {
+		var notification struct {
+			Method string                   `json:"method"`
+			Params PublishDiagnosticsParams `json:"params"`
+		}
+		body := m[strings.Index(m, "\r\n\r\n")+4:]
+		if err := json.Unmarshal([]byte(body), &notification); err != nil || notification.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		messages := make([]string, 0)
+		for _, d := range notification.Params.Diagnostics {
+			messages = append(messages, d.Message)
+		}
+		published[notification.Params.URI] = messages
+	}
+	return published
 }
 
 func MinifyJSON(input string) (string, error) {
@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"sync"
 
 	"github.com/tacsiazuma/structurizr-lsp/parser"
 	"github.com/tacsiazuma/structurizr-lsp/rpc"
@@ -16,11 +17,31 @@ type Lsp struct {
 	rpc         *rpc.Rpc
 	logger      *log.Logger
 	content     map[string]Content
+	encoding    PositionEncodingKind
+	includer    parser.Includer
+	cache       *cachingIncluder
+	analyses    map[string]*analysis
+	watchFiles  bool
+	// edits the client is able to apply
+	workspaceEdit WorkspaceEditClientCapabilities
+	// watchers are registered and the patterns of the referenced files watched
+	watching   bool
+	referenced []string
+	progress   bool
+	folders    []string
+	requestID  int
+	pending    map[int]func(json.RawMessage, *rpc.Error)
+	// lines of the files on disk read during a batch of position conversions
+	lines map[string][]string
+	// guards the state shared with the background indexing
+	mu sync.Mutex
 }
 
 func From(input io.Reader, output io.Writer, logger *log.Logger) *Lsp {
 	r := rpc.NewRpc(input, output, logger)
-	return &Lsp{rpc: r, logger: logger, content: make(map[string]Content)}
+	l := &Lsp{rpc: r, logger: logger, content: make(map[string]Content), analyses: make(map[string]*analysis), pending: make(map[int]func(json.RawMessage, *rpc.Error)), encoding: UTF16}
+	l.setIncluder(parser.NewIncluder())
+	return l
 }
 
 func (l *Lsp) sendError(id int, code int, message string) {
@@ -37,6 +58,63 @@ func (l *Lsp) sendError(id int, code int, message string) {
 	}
 }
 
+func (l *Lsp) sendResponse(id int, result interface{}) {
+	response := rpc.Response{
+		Jsonrpc: "2.0",
+		ID:      id,
+		Result:  result,
+	}
+	if err := l.rpc.WriteMessage(response); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to send response: %v\n", err)
+	}
+}
+
+// Sends a request to the client, the optional callback is run when the client responds.
+func (l *Lsp) sendRequest(method string, params interface{}, onResponse func(json.RawMessage, *rpc.Error)) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		l.logger.Printf("Failed to marshal '%s' params: %v", method, err)
+		return
+	}
+	l.requestID++
+	if onResponse != nil {
+		l.pending[l.requestID] = onResponse
+	}
+	request := rpc.Request{
+		Jsonrpc: "2.0",
+		ID:      l.requestID,
+		Method:  method,
+		Params:  raw,
+	}
+	if err := l.rpc.WriteMessage(request); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to send request: %v\n", err)
+	}
+}
+
+func (l *Lsp) sendNotification(method string, params interface{}) {
+	notification := rpc.Notification{
+		Method: method,
+		Params: params,
+	}
+	if err := l.rpc.WriteMessage(notification); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to send notification: %v\n", err)
+	}
+}
+
+// Response of the client to a request of the server, the result is decoded by the callback of the request.
+type clientResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpc.Error      `json:"error"`
+}
+
+func (l *Lsp) handleResponse(response clientResponse) {
+	if onResponse, ok := l.pending[response.ID]; ok {
+		delete(l.pending, response.ID)
+		onResponse(response.Result, response.Error)
+	}
+}
+
 func (l *Lsp) Handle() error {
 	// Read message from client
 	msg, err := l.rpc.ReadMessage()
@@ -50,16 +128,38 @@ func (l *Lsp) Handle() error {
 		return fmt.Errorf("Failed to parse JSON: %v", err)
 	}
 
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// files on disk may change between messages only
+	defer l.batchConversions()()
 	// Handle the request
 	switch req.Method {
 	case "initialize":
-		l.handleInitialize(req)
+		var params InitializeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'initialize' params: %v", err)
+		}
+		l.handleInitialize(req, params)
 	case "initialized": // notification does not require response
-		return nil
-	case "textDocument/didSave": // notification does not require response
-		return nil
-	case "textDocument/didClose": // notification does not require response
-		return nil
+		l.handleInitialized()
+	case "": // responses to our own requests
+		var response clientResponse
+		if err := json.Unmarshal([]byte(msg), &response); err != nil {
+			return fmt.Errorf("Failed to parse response: %v", err)
+		}
+		l.handleResponse(response)
+	case "textDocument/didSave":
+		var params DidSaveTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'didSave' params: %v", err)
+		}
+		l.handleDidSave(params)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'didClose' params: %v", err)
+		}
+		l.handleDidClose(params)
 	case "$/cancelRequest": // not implemented yet
 		return nil
 	case "textDocument/formatting": // notification does not require response
@@ -68,20 +168,94 @@ func (l *Lsp) Handle() error {
 			return fmt.Errorf("Failed to parse 'inlayHint' params: %v", err)
 		}
 		l.handleFormatting(req.ID, params)
-	case "textDocument/completion": // not implemented yet
-		return nil
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'completion' params: %v", err)
+		}
+		l.handleCompletion(req.ID, params)
+	case "completionItem/resolve":
+		l.handleCompletionResolve(req.ID, req.Params)
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'hover' params: %v", err)
+		}
+		l.handleHover(req.ID, params)
 	case "textDocument/inlayHint":
 		var params InlayHintParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			return fmt.Errorf("Failed to parse 'inlayHint' params: %v", err)
 		}
 		l.handleInlayHint(req.ID, params)
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'definition' params: %v", err)
+		}
+		l.handleDefinition(req.ID, params)
+	case "textDocument/codeAction":
+		var params CodeActionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			// the client waits for the actions even when it sent something unexpected
+			l.sendResponse(req.ID, make([]CodeAction, 0))
+			return fmt.Errorf("Failed to parse 'codeAction' params: %v", err)
+		}
+		l.handleCodeAction(req.ID, params)
+	case "workspace/executeCommand":
+		var params ExecuteCommandParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'executeCommand' params: %v", err)
+		}
+		l.handleExecuteCommand(req.ID, params)
+	case "textDocument/documentHighlight":
+		var params TextDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'documentHighlight' params: %v", err)
+		}
+		l.handleDocumentHighlight(req.ID, params)
+	case "textDocument/documentLink":
+		var params DocumentLinkParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'documentLink' params: %v", err)
+		}
+		l.handleDocumentLink(req.ID, params)
+	case "textDocument/foldingRange":
+		var params FoldingRangeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'foldingRange' params: %v", err)
+		}
+		l.handleFoldingRange(req.ID, params)
+	case "textDocument/selectionRange":
+		var params SelectionRangeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'selectionRange' params: %v", err)
+		}
+		l.handleSelectionRange(req.ID, params)
+	case viewContentsMethod:
+		var params ViewContentsParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'viewContents' params: %v", err)
+		}
+		l.handleViewContents(req.ID, params)
 	case "textDocument/didChange":
 		var params DidChangeTextDocumentParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			return fmt.Errorf("Failed to parse 'didChange' params: %v", err)
 		}
 		l.handleDidChange(params)
+	case "workspace/didChangeWorkspaceFolders":
+		var params DidChangeWorkspaceFoldersParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'didChangeWorkspaceFolders' params: %v", err)
+		}
+		l.handleDidChangeWorkspaceFolders(params)
+	case "workspace/didChangeWatchedFiles":
+		var params DidChangeWatchedFilesParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("Failed to parse 'didChangeWatchedFiles' params: %v", err)
+		}
+		l.handleDidChangeWatchedFiles(params)
 	case "textDocument/didOpen":
 		var params DidOpenTextDocumentParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
@@ -102,5 +276,9 @@ func (l *Lsp) Handle() error {
 
 type Content struct {
 	Text string
-	Ast  *parser.ASTNode
+	// Text split once for the position conversions
+	Lines     []string
+	Ast       *parser.ASTNode
+	Workspace *parser.Workspace
+	Graph     *parser.IncludeGraph
 }
@@ -0,0 +1,89 @@
+package lsp
+
+import (
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+// Expands the selection at each position from the token to its statement, the block of the statement and the
+// enclosing blocks up to the workspace.
+func (l *Lsp) handleSelectionRange(id int, params SelectionRangeParams) {
+	result := make([]*SelectionRange, 0)
+	content, ok := l.content[params.TextDocument.URI]
+	path := pathFromURI(params.TextDocument.URI)
+	for _, pos := range params.Positions {
+		var selection *SelectionRange
+		if ok && content.Ast != nil {
+			selection = l.toSelectionRange(selectionRanges(content.Ast, l.fromPosition(path, pos)))
+		}
+		if selection == nil {
+			// an empty range keeps the results aligned with the positions
+			selection = &SelectionRange{Range: Range{Start: pos, End: pos}}
+		}
+		result = append(result, selection)
+	}
+	l.sendResponse(id, result)
+}
+
+// Links the ranges from the innermost to the outermost one, ranges equal to their inner one are left out.
+func (l *Lsp) toSelectionRange(ranges []parser.Range) *SelectionRange {
+	var outer *SelectionRange
+	for i := len(ranges) - 1; i >= 0; i-- {
+		if i > 0 && ranges[i] == ranges[i-1] {
+			continue
+		}
+		outer = &SelectionRange{Range: l.toLocation(ranges[i]).Range, Parent: outer}
+	}
+	return outer
+}
+
+// Returns the ranges containing the location from the innermost to the outermost one.
+func selectionRanges(root *parser.ASTNode, loc parser.Location) []parser.Range {
+	ranges := make([]parser.Range, 0)
+	node := nodeAt(root, loc)
+	if node == nil {
+		return ranges
+	}
+	header := parser.Range{Start: node.Range.Start, End: node.End}
+	if contains(parser.Range{Start: node.Location, End: node.End}, loc) {
+		ranges = append(ranges, parser.Range{Start: node.Location, End: node.End})
+	}
+	for _, a := range node.Attributes {
+		if a.Location.Source != loc.Source {
+			continue
+		}
+		if contains(parser.Range{Start: a.Location, End: a.End}, loc) {
+			ranges = append(ranges, parser.Range{Start: a.Location, End: a.End})
+		}
+		if header.End.Before(a.End) {
+			header.End = a.End
+		}
+	}
+	ranges = append(ranges, header)
+	for n := node; n != nil && n.Kind != parser.NodeRoot; n = n.Parent {
+		if contains(n.Range, loc) {
+			ranges = append(ranges, n.Range)
+		}
+	}
+	return ranges
+}
+
+// Returns the innermost node whose range contains the location, braces belong to the statement of their block.
+func nodeAt(node *parser.ASTNode, loc parser.Location) *parser.ASTNode {
+	for _, c := range node.Children {
+		if c.Kind == parser.NodeBlockStart || c.Kind == parser.NodeBlockEnd || !contains(c.Range, loc) {
+			continue
+		}
+		if inner := nodeAt(c, loc); inner != nil {
+			return inner
+		}
+	}
+	if node.Kind == parser.NodeRoot || !contains(node.Range, loc) {
+		return nil
+	}
+	return node
+}
+
+// The end of the range is part of it as editors place the cursor after the last character.
+func contains(r parser.Range, loc parser.Location) bool {
+	return loc.Source == r.Start.Source && !loc.Before(r.Start) && !r.End.Before(loc)
+}
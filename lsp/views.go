@@ -0,0 +1,52 @@
+package lsp
+
+import (
+	"slices"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+// Custom request returning the elements and relationships of a view resolved from its include and exclude statements.
+const viewContentsMethod = "structurizr/viewContents"
+
+// Responds with the contents of the view, or null when the document has no such view.
+func (l *Lsp) handleViewContents(id int, params ViewContentsParams) {
+	path := pathFromURI(params.TextDocument.URI)
+	for _, ws := range l.workspacesOf(path) {
+		views := ws.Views()
+		if views == nil {
+			continue
+		}
+		var view *parser.View
+		if params.Key != "" {
+			view = views.View(params.Key)
+		} else if params.Position != nil {
+			view = views.ViewAt(l.fromPosition(path, *params.Position))
+		}
+		if view != nil {
+			l.sendResponse(id, l.viewContents(view, ws.Model))
+			return
+		}
+	}
+	l.sendResponse(id, nil)
+}
+
+func (l *Lsp) viewContents(view *parser.View, model *parser.Model) ViewContents {
+	elements, relationships := view.Contents(model)
+	contents := ViewContents{Key: view.Key, Type: view.Type, Elements: make([]ViewElement, 0), Relationships: make([]ViewRelationship, 0)}
+	for _, e := range elements {
+		contents.Elements = append(contents.Elements, ViewElement{Identifier: e.Identifier, Name: e.Name, Type: e.Type, Tags: e.Tags, Location: l.toLocation(e.Definition)})
+	}
+	for _, r := range relationships {
+		contents.Relationships = append(contents.Relationships, ViewRelationship{
+			Identifier:  r.Identifier,
+			Source:      slices.Index(elements, r.Source),
+			Destination: slices.Index(elements, r.Destination),
+			Description: r.Description,
+			Technology:  r.Technology,
+			Tags:        r.Tags,
+			Location:    l.toLocation(r.Definition),
+		})
+	}
+	return contents
+}
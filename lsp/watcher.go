@@ -0,0 +1,149 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/tacsiazuma/structurizr-lsp/parser"
+)
+
+// Files changed outside of the editor affect the analysis of the documents including them.
+var watchers = []FileSystemWatcher{
+	{GlobPattern: "**/*.dsl"},
+}
+
+const referencedWatchersID = "structurizr-referenced-files"
+
+func (l *Lsp) registerWatchers() {
+	if !l.watchFiles {
+		return
+	}
+	l.register("structurizr-watched-files", watchers)
+	l.watching = true
+	l.watchReferencedFiles()
+}
+
+// Reports whether the client reports the changes of the file: the .dsl files and directories of the workspace folders
+// and the files referenced by the analysed workspaces once they are registered. Remote files are never watched.
+func (l *Lsp) watched(path string) bool {
+	if !l.watching || isRemote(path) {
+		return false
+	}
+	if slices.Contains(l.referenced, filepath.ToSlash(path)) {
+		return true
+	}
+	if !l.inFolders(path) {
+		// the relative glob only matches within the workspace folders
+		return false
+	}
+	if strings.EqualFold(filepath.Ext(path), ".dsl") {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// Documentation, JSON workspaces and themes are watched only while an analysed workspace refers to them, the registration
+// is replaced whenever they change.
+func (l *Lsp) watchReferencedFiles() {
+	if !l.watching {
+		return
+	}
+	patterns := l.referencedFiles()
+	if slices.Equal(patterns, l.referenced) {
+		return
+	}
+	if len(l.referenced) > 0 {
+		l.sendRequest("client/unregisterCapability", UnregistrationParams{
+			Unregisterations: []Unregistration{{ID: referencedWatchersID, Method: "workspace/didChangeWatchedFiles"}},
+		}, nil)
+	}
+	l.referenced = patterns
+	if len(patterns) == 0 {
+		return
+	}
+	referenced := make([]FileSystemWatcher, 0, len(patterns))
+	for _, p := range patterns {
+		referenced = append(referenced, FileSystemWatcher{GlobPattern: p})
+	}
+	l.register(referencedWatchersID, referenced)
+}
+
+// Returns the glob patterns of the documentation, the extended JSON workspaces and the local themes of the analysed
+// documents.
+func (l *Lsp) referencedFiles() []string {
+	patterns := make([]string, 0)
+	add := func(pattern string) {
+		if !slices.Contains(patterns, pattern) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	for _, a := range l.analyses {
+		for _, doc := range a.graph.Documentation() {
+			add(filepath.ToSlash(doc))
+			// directories are read with every file in them
+			add(filepath.ToSlash(doc) + "/*")
+		}
+		for base := a.graph.Base(); base != nil; base = base.Base() {
+			if strings.HasSuffix(strings.ToLower(base.Root), ".json") {
+				add(filepath.ToSlash(base.Root))
+			}
+		}
+		if a.ast != nil {
+			for _, theme := range localThemes(a.ast, nil) {
+				add(filepath.ToSlash(theme))
+			}
+		}
+	}
+	slices.Sort(patterns)
+	return patterns
+}
+
+// Collects the resolved paths of the themes which are neither built-in nor remote.
+func localThemes(node *parser.ASTNode, themes []string) []string {
+	if n, ok := node.Typed().(*parser.ThemeNode); ok {
+		for _, t := range n.Themes() {
+			resolved := parser.ResolvePath(t.Location.Source, t.Content)
+			if t.Content != "default" && !isRemote(resolved) {
+				themes = append(themes, resolved)
+			}
+		}
+	}
+	for _, c := range node.Children {
+		themes = localThemes(c, themes)
+	}
+	return themes
+}
+
+func (l *Lsp) register(id string, watchers []FileSystemWatcher) {
+	l.sendRequest("client/registerCapability", RegistrationParams{
+		Registrations: []Registration{{
+			ID:              id,
+			Method:          "workspace/didChangeWatchedFiles",
+			RegisterOptions: DidChangeWatchedFilesRegistrationOptions{Watchers: watchers},
+		}},
+	}, nil)
+}
+
+// Changes on disk invalidate the cached includes and trigger the analysis of every document depending on them.
+// Open documents are left alone as their buffer takes precedence over the disk.
+func (l *Lsp) handleDidChangeWatchedFiles(params DidChangeWatchedFilesParams) {
+	for _, change := range params.Changes {
+		path := pathFromURI(change.URI)
+		l.cache.invalidate(path)
+		if _, open := l.content[change.URI]; open {
+			continue
+		}
+		if _, root := l.analyses[path]; root {
+			if change.Type == FileDeleted {
+				delete(l.analyses, path)
+			} else {
+				l.analyseRoot(path)
+			}
+		}
+		l.analyseDependents(path)
+	}
+	l.watchReferencedFiles()
+}
@@ -0,0 +1,127 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/tacsiazuma/structurizr-lsp/rpc"
+)
+
+// Name of the files discovered as workspace roots in the workspace folders.
+const workspaceFile = "workspace.dsl"
+
+func (l *Lsp) handleDidChangeWorkspaceFolders(params DidChangeWorkspaceFoldersParams) {
+	for _, f := range params.Event.Removed {
+		l.removeFolder(pathFromURI(f.URI))
+	}
+	added := make([]string, 0)
+	for _, f := range params.Event.Added {
+		folder := pathFromURI(f.URI)
+		if !slices.Contains(l.folders, folder) {
+			l.folders = append(l.folders, folder)
+			added = append(added, folder)
+		}
+	}
+	l.startIndexing(added)
+}
+
+// Forgets the documents of a removed folder which are not open and clears their diagnostics.
+func (l *Lsp) removeFolder(folder string) {
+	l.folders = slices.DeleteFunc(l.folders, func(f string) bool { return f == folder })
+	for root, a := range l.analyses {
+		if _, open := l.content[uriFromPath(root)]; open || !inFolder(root, folder) {
+			continue
+		}
+		delete(l.analyses, root)
+		l.publishDiagnostics(a.graph, nil)
+	}
+}
+
+// Indexes the folders in the background, reporting progress when the client supports it.
+func (l *Lsp) startIndexing(folders []string) {
+	if len(folders) == 0 {
+		return
+	}
+	if !l.progress {
+		go l.indexFolders(folders, "")
+		return
+	}
+	token := fmt.Sprintf("structurizr-indexing-%d", l.requestID+1)
+	l.sendRequest("window/workDoneProgress/create", WorkDoneProgressCreateParams{Token: token}, func(_ json.RawMessage, err *rpc.Error) {
+		if err != nil {
+			token = ""
+		}
+		go l.indexFolders(folders, token)
+	})
+}
+
+// Analyses every workspace file found in the folders which has not been analysed yet, so cross-file features work
+// before the documents are opened.
+func (l *Lsp) indexFolders(folders []string, token string) {
+	roots := discoverWorkspaces(folders)
+	l.mu.Lock()
+	l.reportProgress(token, WorkDoneProgress{Kind: "begin", Title: "Indexing Structurizr workspaces", Percentage: percentage(0, len(roots))})
+	l.mu.Unlock()
+	for i, root := range roots {
+		l.mu.Lock()
+		if _, known := l.analyses[root]; !known && l.inFolders(root) {
+			end := l.batchConversions()
+			l.analyseRoot(root)
+			end()
+		}
+		l.reportProgress(token, WorkDoneProgress{Kind: "report", Message: root, Percentage: percentage(i+1, len(roots))})
+		l.mu.Unlock()
+	}
+	l.mu.Lock()
+	l.reportProgress(token, WorkDoneProgress{Kind: "end"})
+	l.mu.Unlock()
+}
+
+func (l *Lsp) reportProgress(token string, value WorkDoneProgress) {
+	if token == "" {
+		return
+	}
+	l.sendNotification("$/progress", ProgressParams{Token: token, Value: value})
+}
+
+// Folders may be removed while indexing them.
+func (l *Lsp) inFolders(path string) bool {
+	return slices.ContainsFunc(l.folders, func(folder string) bool { return inFolder(path, folder) })
+}
+
+func inFolder(path, folder string) bool {
+	rel, err := filepath.Rel(folder, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Returns the workspace files of the folders in lexical order, hidden directories are skipped.
+func discoverWorkspaces(folders []string) []string {
+	roots := make([]string, 0)
+	for _, folder := range folders {
+		_ = filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() && path != folder && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && d.Name() == workspaceFile && !slices.Contains(roots, path) {
+				roots = append(roots, path)
+			}
+			return nil
+		})
+	}
+	return roots
+}
+
+func percentage(done, total int) *int {
+	p := 100
+	if total > 0 {
+		p = done * 100 / total
+	}
+	return &p
+}
@@ -0,0 +1,342 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NodeKind tells what a node of the syntax tree represents, assigned by the parser based on the keyword and the
+// enclosing block.
+type NodeKind string
+
+const (
+	NodeRoot          NodeKind = "root"
+	NodeWorkspace     NodeKind = "workspace"
+	NodeModel         NodeKind = "model"
+	NodeArchetypes    NodeKind = "archetypes"
+	NodeViews         NodeKind = "views"
+	NodeConfiguration NodeKind = "configuration"
+	NodeElement       NodeKind = "element"
+	NodeRelationship  NodeKind = "relationship"
+	NodeView          NodeKind = "view"
+	NodeDirective     NodeKind = "directive"
+	NodeProperties    NodeKind = "properties"
+	NodeProperty      NodeKind = "property"
+	NodeAssignment    NodeKind = "assignment"
+	NodeIdentifier    NodeKind = "identifier"
+	NodeStatement     NodeKind = "statement"
+	NodeBlockStart    NodeKind = "{"
+	NodeBlockEnd      NodeKind = "}"
+)
+
+// TokenRole is the meaning of a token within its statement, e.g. the name or the technology of a container.
+type TokenRole string
+
+const (
+	RoleNone        TokenRole = ""
+	RoleName        TokenRole = "name"
+	RoleDescription TokenRole = "description"
+	RoleTechnology  TokenRole = "technology"
+	RoleTags        TokenRole = "tags"
+	RoleValue       TokenRole = "value"
+	RoleIdentifier  TokenRole = "identifier"
+	RoleSource      TokenRole = "source"
+	RoleDestination TokenRole = "destination"
+	RoleOption      TokenRole = "option"
+	RolePath        TokenRole = "path"
+	RoleImporter    TokenRole = "importer"
+	RoleInstances   TokenRole = "instances"
+	RoleMetadata    TokenRole = "metadata"
+	RoleScope       TokenRole = "scope"
+	RoleEnvironment TokenRole = "environment"
+	RoleKey         TokenRole = "key"
+	RoleBase        TokenRole = "base"
+	RoleTarget      TokenRole = "target"
+	RoleExpression  TokenRole = "expression"
+	// External script or plugin, the language of inline scripts and the class name of plugins have it too
+	RoleFile TokenRole = "file"
+)
+
+// Range spans from the start of the first token to the end of the last token of a node, including its block.
+type Range struct {
+	Start Location
+	End   Location
+}
+
+// Contains reports whether the location is inside the range.
+func (r Range) Contains(loc Location) bool {
+	if loc.Source != r.Start.Source {
+		return false
+	}
+	return !loc.Before(r.Start) && loc.Before(r.End)
+}
+
+type ASTNode struct {
+	Token
+	Parent     *ASTNode
+	Kind       NodeKind
+	Value      string
+	Range      Range
+	Attributes []*Token
+	Children   []*ASTNode
+	// typed view of the node, kept up to date with its kind
+	typed Node
+}
+
+var elementRoles = map[string][]TokenRole{
+	"person":                 {RoleName, RoleDescription, RoleTags},
+	"softwareSystem":         {RoleName, RoleDescription, RoleTags},
+	"container":              {RoleName, RoleDescription, RoleTechnology, RoleTags},
+	"component":              {RoleName, RoleDescription, RoleTechnology, RoleTags},
+	"group":                  {RoleName},
+	"enterprise":             {RoleName},
+	"deploymentEnvironment":  {RoleName},
+	"deploymentGroup":        {RoleName},
+	"deploymentNode":         {RoleName, RoleDescription, RoleTechnology, RoleInstances, RoleTags},
+	"infrastructureNode":     {RoleName, RoleDescription, RoleTechnology, RoleTags},
+	"softwareSystemInstance": {RoleIdentifier},
+	"containerInstance":      {RoleIdentifier},
+	"element":                {RoleName, RoleMetadata, RoleDescription, RoleTags},
+}
+
+var viewRoles = map[string][]TokenRole{
+	"systemLandscape": {RoleKey, RoleDescription},
+	"systemContext":   {RoleScope, RoleKey, RoleDescription},
+	"container":       {RoleScope, RoleKey, RoleDescription},
+	"component":       {RoleScope, RoleKey, RoleDescription},
+	"filtered":        {RoleScope, RoleOption, RoleTags, RoleKey, RoleDescription},
+	"dynamic":         {RoleScope, RoleKey, RoleDescription},
+	"deployment":      {RoleScope, RoleEnvironment, RoleKey, RoleDescription},
+	"custom":          {RoleKey, RoleName, RoleDescription},
+	"image":           {RoleScope, RoleKey},
+}
+
+var directiveRoles = map[string][]TokenRole{
+	"!identifiers":          {RoleOption},
+	"!impliedRelationships": {RoleOption},
+	"!include":              {RolePath},
+	"!docs":                 {RolePath, RoleImporter},
+	"!adrs":                 {RolePath, RoleImporter},
+	"!element":              {RoleTarget},
+	"!ref":                  {RoleTarget},
+	"!extend":               {RoleTarget},
+	"!relationship":         {RoleTarget},
+	"!elements":             {RoleExpression},
+	"!relationships":        {RoleExpression},
+	"!script":               {RoleFile},
+	"!plugin":               {RoleFile},
+}
+
+// Directives reopening an existing element, their blocks may define elements like the element itself.
+var elementDirectives = map[string]bool{
+	"!element": true,
+	"!ref":     true,
+	"!extend":  true,
+}
+
+var relationshipRoles = []TokenRole{RoleSource, RoleDestination, RoleDescription, RoleTechnology, RoleTags}
+
+// Keywords opening a block of key-value pairs.
+var propertyBlocks = map[string]bool{
+	"properties":   true,
+	"perspectives": true,
+	"users":        true,
+}
+
+// Assigns the kind of the node and the roles of its attributes based on its keyword and the enclosing block.
+// Archetypes map their names to the element types they are based on.
+func classify(node *ASTNode, parent *ASTNode, archetypes map[string]string) {
+	content := node.Token.Content
+	switch {
+	case parent.Kind == NodeProperties:
+		node.Kind = NodeProperty
+		if node.Token.Type == TokenString {
+			node.Token.Role = RoleName
+			assignRoles(node, RoleValue)
+		} else {
+			assignRoles(node, RoleOption)
+		}
+	case node.Token.Type != TokenKeyword:
+		node.Kind = NodeStatement
+	case strings.HasPrefix(content, "!"):
+		node.Kind = NodeDirective
+		assignRoles(node, directiveRoles[content]...)
+	case propertyBlocks[content]:
+		node.Kind = NodeProperties
+	case parent.Kind == NodeRoot && content == "workspace":
+		node.Kind = NodeWorkspace
+		if len(node.Attributes) > 0 && node.Attributes[0].Content == "extends" && node.Attributes[0].Type == TokenKeyword {
+			assignRoles(node, RoleNone, RoleBase)
+		} else {
+			assignRoles(node, RoleName, RoleDescription)
+		}
+	case parent.Kind == NodeWorkspace && content == "model":
+		node.Kind = NodeModel
+	case parent.Kind == NodeWorkspace && content == "views":
+		node.Kind = NodeViews
+	case parent.Kind == NodeWorkspace && content == "configuration":
+		node.Kind = NodeConfiguration
+	case parent.Kind == NodeModel && content == "archetypes":
+		node.Kind = NodeArchetypes
+	case (parent.Kind == NodeModel || parent.Kind == NodeElement || parent.Kind == NodeArchetypes || parent.Kind == NodeDirective && elementDirectives[parent.Content]) && elementType(content, archetypes) != "":
+		node.Kind = NodeElement
+		assignRoles(node, elementRoles[elementType(content, archetypes)]...)
+	case parent.Kind == NodeViews && viewRoles[content] != nil:
+		node.Kind = NodeView
+		assignRoles(node, viewRoles[content]...)
+	default:
+		node.Kind = NodeStatement
+	}
+	node.typed = newTyped(node)
+}
+
+// Classifies the statements of the block again with the archetypes of an extended workspace, which are only known
+// once the base has been analysed. Archetypes defined within the block are collected like the parser does.
+func reclassify(node *ASTNode, archetypes map[string]string) {
+	for _, c := range node.Children {
+		target := c
+		if c.Kind == NodeAssignment && len(c.Children) > 1 {
+			target = c.Children[len(c.Children)-1]
+		}
+		if target.Kind == NodeStatement && target.Token.Type == TokenKeyword {
+			classify(target, node, archetypes)
+		}
+		if node.Kind == NodeArchetypes && target != c && target.Kind == NodeElement {
+			archetypes[strings.ToLower(c.Children[0].Content)] = elementType(target.Content, archetypes)
+		}
+		reclassify(target, archetypes)
+	}
+}
+
+// Returns the element type of a keyword, which is either an element type or the name of an archetype.
+func elementType(keyword string, archetypes map[string]string) string {
+	if elementRoles[keyword] != nil {
+		return keyword
+	}
+	return archetypes[strings.ToLower(keyword)]
+}
+
+// Builds a relationship node from a line containing the relationship operator, the source is implicit when the
+// line starts with the operator.
+func newRelationship(operator *Token, tokens []*Token) *ASTNode {
+	node := NewNode(operator, NodeRelationship)
+	for _, t := range tokens {
+		if t.Type == TokenKeyword || t.Type == TokenString {
+			node.Attributes = append(node.Attributes, t)
+		}
+	}
+	roles := relationshipRoles
+	if tokens[0] == operator {
+		roles = roles[1:]
+	}
+	assignRoles(node, roles...)
+	return node
+}
+
+func assignRoles(node *ASTNode, roles ...TokenRole) {
+	for i, a := range node.Attributes {
+		if i < len(roles) {
+			a.Role = roles[i]
+		}
+	}
+}
+
+// Returns the first attribute with the given role.
+func (n *ASTNode) Attribute(role TokenRole) *Token {
+	for _, a := range n.Attributes {
+		if a.Role == role {
+			return a
+		}
+	}
+	return nil
+}
+
+// Extends the ranges of the node and its children over the tokens of the same source file.
+func computeRanges(node *ASTNode) {
+	node.Range = Range{Start: node.Location, End: node.End}
+	for _, a := range node.Attributes {
+		node.Range.extend(a.Location, a.End)
+	}
+	for _, c := range node.Children {
+		computeRanges(c)
+		node.Range.extend(c.Range.Start, c.Range.End)
+	}
+}
+
+func (r *Range) extend(start Location, end Location) {
+	if start.Source != r.Start.Source {
+		return
+	}
+	if start.Before(r.Start) {
+		r.Start = start
+	}
+	if r.End.Before(end) {
+		r.End = end
+	}
+}
+
+func NewNode(token *Token, kind NodeKind) *ASTNode {
+	node := &ASTNode{Token: *token, Kind: kind, Attributes: make([]*Token, 0), Children: make([]*ASTNode, 0)}
+	node.typed = newTyped(node)
+	return node
+}
+
+func displayTree(node *ASTNode, prefix string, isLast bool) string {
+	var connector string
+	var sb strings.Builder
+	if isLast {
+		connector = "└──"
+	} else {
+		connector = "├──"
+	}
+	sb.WriteString(fmt.Sprintf("%s%s %s (Attributes: %v)\n", prefix, connector, string(node.Kind)+" "+node.Content, node.Attributes))
+	newPrefix := prefix
+	if isLast {
+		newPrefix += "    "
+	} else {
+		newPrefix += "│   "
+	}
+
+	for i, child := range node.Children {
+		sb.WriteString(displayTree(child, newPrefix, i == len(node.Children)-1))
+	}
+	return sb.String()
+}
+
+func (n *ASTNode) ToString() string {
+	attributes := mapToString(n.Attributes)
+	children := ""
+	if n.Children != nil {
+		for _, c := range n.Children {
+			children += c.ToString()
+		}
+	}
+	return fmt.Sprintf("(%s %s %s)", n.Content, attributes, children)
+}
+
+func (n *ASTNode) AddChild(c *ASTNode) {
+	c.Parent = n
+	n.Children = append(n.Children, c)
+}
+
+func (n *ASTNode) HasChild(t TokenType) bool {
+	for _, c := range n.Children {
+		if c.Token.Type == t {
+			return true
+		}
+	}
+	return false
+
+}
+
+func mapToString(m []*Token) string {
+	var builder strings.Builder
+
+	for _, value := range m {
+		builder.WriteString(fmt.Sprintf("(%s) ", value.Content))
+	}
+
+	// Trim the trailing space
+	result := strings.TrimSpace(builder.String())
+	return result
+}
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAst(t *testing.T) {
+	file := "test.dsl"
+	fake := &FakeIncluder{}
+	t.Run("nodes are classified by keyword and enclosing block", func(t *testing.T) {
+		sut := New(file, "workspace {\nmodel {\nu = person \"User\"\n}\nviews {\ncontainer system \"key\" {\ninclude *\n}\n}\n}", fake)
+		ast, _ := sut.Parse()
+		workspace := ast.Children[0]
+		model := workspace.Children[1]
+		assignment := model.Children[1]
+		views := workspace.Children[2]
+		view := views.Children[1]
+		assert.Equal(t, NodeRoot, ast.Kind)
+		assert.Equal(t, NodeWorkspace, workspace.Kind)
+		assert.Equal(t, NodeModel, model.Kind)
+		assert.Equal(t, NodeAssignment, assignment.Kind)
+		assert.Equal(t, NodeIdentifier, assignment.Children[0].Kind)
+		assert.Equal(t, NodeElement, assignment.Children[1].Kind)
+		assert.Equal(t, NodeViews, views.Kind)
+		assert.Equal(t, NodeView, view.Kind)
+		assert.Equal(t, NodeStatement, view.Children[1].Kind)
+	})
+	t.Run("attributes get their roles from the element type", func(t *testing.T) {
+		sut := New(file, "workspace {\nmodel {\ns = softwareSystem \"System\" {\ncontainer \"Api\" \"Serves\" \"Go\" \"Tag\"\n}\n}\n}", fake)
+		ast, _ := sut.Parse()
+		container := ast.Children[0].Children[1].Children[1].Children[1].Children[1]
+		assert.Equal(t, NodeElement, container.Kind)
+		assert.Equal(t, "Api", container.Attribute(RoleName).Content)
+		assert.Equal(t, "Serves", container.Attribute(RoleDescription).Content)
+		assert.Equal(t, "Go", container.Attribute(RoleTechnology).Content)
+		assert.Equal(t, "Tag", container.Attribute(RoleTags).Content)
+	})
+	t.Run("relationships with explicit source", func(t *testing.T) {
+		sut := New(file, "workspace {\nmodel {\na -> b \"Uses\" \"HTTP\"\n}\n}", fake)
+		ast, diagnostics := sut.Parse()
+		assert.Empty(t, diagnostics)
+		rel := ast.Children[0].Children[1].Children[1]
+		assert.Equal(t, NodeRelationship, rel.Kind)
+		assert.Equal(t, "a", rel.Attribute(RoleSource).Content)
+		assert.Equal(t, "b", rel.Attribute(RoleDestination).Content)
+		assert.Equal(t, "Uses", rel.Attribute(RoleDescription).Content)
+		assert.Equal(t, "HTTP", rel.Attribute(RoleTechnology).Content)
+	})
+	t.Run("relationships with implicit source", func(t *testing.T) {
+		sut := New(file, "a = person {\n-> b \"Uses\"\n}", fake)
+		ast, _ := sut.Parse()
+		rel := ast.Children[0].Children[1].Children[1]
+		assert.Equal(t, NodeRelationship, rel.Kind)
+		assert.Nil(t, rel.Attribute(RoleSource))
+		assert.Equal(t, "b", rel.Attribute(RoleDestination).Content)
+		assert.Equal(t, "Uses", rel.Attribute(RoleDescription).Content)
+	})
+	t.Run("assigned relationships", func(t *testing.T) {
+		sut := New(file, "r = a -> b", fake)
+		ast, _ := sut.Parse()
+		assert.Equal(t, NodeAssignment, ast.Children[0].Kind)
+		assert.Equal(t, NodeRelationship, ast.Children[0].Children[1].Kind)
+	})
+	t.Run("property blocks contain properties", func(t *testing.T) {
+		sut := New(file, "workspace {\nproperties {\n\"key\" \"value\"\n}\n}", fake)
+		ast, _ := sut.Parse()
+		properties := ast.Children[0].Children[1]
+		assert.Equal(t, NodeProperties, properties.Kind)
+		assert.Equal(t, NodeProperty, properties.Children[1].Kind)
+		assert.Equal(t, RoleName, properties.Children[1].Token.Role)
+		assert.Equal(t, RoleValue, properties.Children[1].Attributes[0].Role)
+	})
+	t.Run("element keywords outside of the model are statements", func(t *testing.T) {
+		sut := New(file, "workspace {\nviews {\nstyles {\nelement \"Person\" {\n}\n}\n}\n}", fake)
+		ast, _ := sut.Parse()
+		styles := ast.Children[0].Children[1].Children[1]
+		assert.Equal(t, NodeStatement, styles.Kind)
+		assert.Equal(t, NodeStatement, styles.Children[1].Kind)
+	})
+	t.Run("directives", func(t *testing.T) {
+		sut := New(file, "workspace {\n!docs docs\n}", fake)
+		ast, _ := sut.Parse()
+		docs := ast.Children[0].Children[1]
+		assert.Equal(t, NodeDirective, docs.Kind)
+		assert.Equal(t, "docs", docs.Attribute(RolePath).Content)
+	})
+	t.Run("ranges cover the statement and its block", func(t *testing.T) {
+		sut := New(file, "workspace {\n  model {\n    u = person \"User\"\n  }\n}", fake)
+		ast, _ := sut.Parse()
+		workspace := ast.Children[0]
+		model := workspace.Children[1]
+		assignment := model.Children[1]
+		assert.Equal(t, Range{Start: Location{Source: file, Line: 0, Pos: 0}, End: Location{Source: file, Line: 4, Pos: 1}}, workspace.Range)
+		assert.Equal(t, Range{Start: Location{Source: file, Line: 1, Pos: 2}, End: Location{Source: file, Line: 3, Pos: 3}}, model.Range)
+		assert.Equal(t, Range{Start: Location{Source: file, Line: 2, Pos: 4}, End: Location{Source: file, Line: 2, Pos: 21}}, assignment.Range)
+		assert.True(t, model.Range.Contains(Location{Source: file, Line: 2, Pos: 0}))
+		assert.False(t, model.Range.Contains(Location{Source: file, Line: 4, Pos: 0}))
+	})
+	t.Run("ranges do not extend into included files", func(t *testing.T) {
+		sut := New("first.dsl", "workspace {\n!include test.dsl\n}", fake)
+		ast, _ := sut.Parse()
+		workspace := ast.Children[0]
+		assert.Equal(t, "test.dsl", workspace.Children[2].Location.Source)
+		assert.Equal(t, Range{Start: Location{Source: "first.dsl", Line: 0, Pos: 0}, End: Location{Source: "first.dsl", Line: 2, Pos: 1}}, workspace.Range)
+	})
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Decision is an architecture decision record read from the directory of !adrs.
+type Decision struct {
+	ID     string
+	Title  string
+	Status string
+	Date   string
+	Path   string
+}
+
+// Importers of !adrs by their short names, which may also be given by the fully qualified name of their class.
+var adrImporters = map[string]string{
+	"adrtools":   "com.structurizr.documentation.importer.AdrToolsDecisionImporter",
+	"madr":       "com.structurizr.documentation.importer.MadrDecisionImporter",
+	"log4brains": "com.structurizr.documentation.importer.Log4bRainsDecisionImporter",
+}
+
+var (
+	fqcnPattern = regexp.MustCompile(`^([a-zA-Z_$][\w$]*\.)+[a-zA-Z_$][\w$]*$`)
+	// adr-tools and MADR number their records, e.g. 0001-record-architecture-decisions.md
+	numberedDecision = regexp.MustCompile(`^(\d+)-.+\.md$`)
+	// adr-tools prefixes the titles with the number of the record, e.g. # 1. Record architecture decisions
+	numberedTitle = regexp.MustCompile(`^\d+\.\s+`)
+	// MADR and log4brains list the status like * Status: accepted or - Status: accepted
+	statusItem = regexp.MustCompile(`^[*-]\s+Status:\s*(.+)$`)
+	// MADR 3 keeps the status in the front matter
+	statusField = regexp.MustCompile(`^status:\s*(.+)$`)
+	dateLine    = regexp.MustCompile(`(?i)^(?:[*-]\s+)?date:\s*(.+)$`)
+)
+
+// Returns the short name of the importer, the default adr-tools importer when none is given. Custom importers are
+// returned as they are, false when the importer is neither known nor a fully qualified class name.
+func adrImporter(importer string) (string, bool) {
+	if importer == "" {
+		return "adrtools", true
+	}
+	for name, fqcn := range adrImporters {
+		if importer == name || importer == fqcn {
+			return name, true
+		}
+	}
+	return importer, fqcnPattern.MatchString(importer)
+}
+
+// Reads the records among the files of the directory with one of the known importers, problems of single records
+// are returned as messages besides the records which could be read.
+func readDecisions(importer string, files []IncludedFile) ([]*Decision, []string) {
+	decisions := make([]*Decision, 0)
+	problems := make([]string, 0)
+	for _, file := range files {
+		name := filepath.Base(file.Path)
+		if !isDecisionFile(importer, name) {
+			continue
+		}
+		d := parseDecision(file.Path, file.Content)
+		d.ID = decisionID(importer, name)
+		if d.Title == "" {
+			problems = append(problems, fmt.Sprintf("ADR %s has no title", name))
+		}
+		if d.Status == "" {
+			problems = append(problems, fmt.Sprintf("ADR %s has no status", name))
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions, problems
+}
+
+// log4brains names its records freely, but keeps its templates and index next to them.
+func isDecisionFile(importer string, name string) bool {
+	if importer != "log4brains" {
+		return numberedDecision.MatchString(name)
+	}
+	switch strings.ToLower(name) {
+	case "readme.md", "index.md", "template.md":
+		return false
+	}
+	return strings.HasSuffix(name, ".md")
+}
+
+func decisionID(importer string, name string) string {
+	if m := numberedDecision.FindStringSubmatch(name); m != nil && importer != "log4brains" {
+		return strings.TrimLeft(m[1], "0")
+	}
+	return strings.TrimSuffix(name, ".md")
+}
+
+// Reads the title from the first heading, the status either from the front matter, a status item or the first line
+// of the Status section.
+func parseDecision(path string, content string) *Decision {
+	d := &Decision{Path: path}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	inStatus := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case d.Title == "" && strings.HasPrefix(line, "# "):
+			d.Title = numberedTitle.ReplaceAllString(strings.TrimSpace(line[2:]), "")
+		case strings.HasPrefix(line, "#"):
+			inStatus = strings.EqualFold(strings.TrimSpace(strings.TrimLeft(line, "#")), "status")
+		case d.Status == "" && statusItem.MatchString(line):
+			d.Status = statusItem.FindStringSubmatch(line)[1]
+		case d.Status == "" && statusField.MatchString(line):
+			d.Status = statusField.FindStringSubmatch(line)[1]
+		case d.Date == "" && dateLine.MatchString(line):
+			d.Date = dateLine.FindStringSubmatch(line)[1]
+		case inStatus && d.Status == "" && line != "":
+			d.Status = line
+		}
+	}
+	d.Status = strings.TrimSpace(d.Status)
+	return d
+}
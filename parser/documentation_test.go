@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentation(t *testing.T) {
+	t.Run("importers are known by their short and class names", func(t *testing.T) {
+		for _, c := range []struct {
+			importer string
+			name     string
+			known    bool
+		}{
+			{"", "adrtools", true},
+			{"madr", "madr", true},
+			{"com.structurizr.documentation.importer.Log4bRainsDecisionImporter", "log4brains", true},
+			{"com.example.Importer", "com.example.Importer", true},
+			{"nygard", "nygard", false},
+		} {
+			name, known := adrImporter(c.importer)
+			assert.Equal(t, c.name, name)
+			assert.Equal(t, c.known, known)
+		}
+	})
+	t.Run("adr-tools records keep their status in a section", func(t *testing.T) {
+		d := parseDecision("0003-use-go.md", "# 3. Use Go\n\nDate: 2024-02-01\n\n## Status\n\nSuperseded by [4. Use Rust](0004-use-rust.md)\n\n## Context\n\nText\n")
+		assert.Equal(t, "Use Go", d.Title)
+		assert.Equal(t, "Superseded by [4. Use Rust](0004-use-rust.md)", d.Status)
+		assert.Equal(t, "2024-02-01", d.Date)
+	})
+	t.Run("MADR records keep their status in the front matter or in a list", func(t *testing.T) {
+		d := parseDecision("0001-x.md", "---\nstatus: accepted\ndate: 2024-03-01\n---\n# Use Markdown\n")
+		assert.Equal(t, "Use Markdown", d.Title)
+		assert.Equal(t, "accepted", d.Status)
+		assert.Equal(t, "2024-03-01", d.Date)
+		d = parseDecision("0001-x.md", "# Use Markdown\n\n* Status: proposed\n* Date: 2024-03-01\n")
+		assert.Equal(t, "proposed", d.Status)
+	})
+	t.Run("log4brains records are every markdown file besides the template and the index", func(t *testing.T) {
+		decisions, problems := readDecisions("log4brains", []IncludedFile{
+			{Path: "adrs/20240101-use-go.md", Content: "# Use Go\n\n- Status: accepted\n"},
+			{Path: "adrs/template.md", Content: "# Title\n"},
+			{Path: "adrs/index.md", Content: "# Decisions\n"},
+		})
+		assert.Empty(t, problems)
+		if assert.Equal(t, 1, len(decisions)) {
+			assert.Equal(t, "20240101-use-go", decisions[0].ID)
+			assert.Equal(t, "accepted", decisions[0].Status)
+		}
+	})
+}
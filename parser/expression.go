@@ -0,0 +1,360 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+)
+
+// Expression selects elements or relationships of the model using the Structurizr expression language, e.g.
+// element.tag==Database, ->api-> or relationship.source==user. Terms may be combined with && and ||.
+type Expression struct {
+	element      func(*Element) bool
+	relationship func(*Relationship) bool
+}
+
+// Names of the element types usable in element.type expressions.
+var expressionTypes = map[string]string{
+	"person":                 "person",
+	"softwaresystem":         "softwareSystem",
+	"container":              "container",
+	"component":              "component",
+	"deploymentnode":         "deploymentNode",
+	"infrastructurenode":     "infrastructureNode",
+	"softwaresysteminstance": "softwareSystemInstance",
+	"containerinstance":      "containerInstance",
+	"custom":                 "element",
+}
+
+// Reports whether the expression selects elements, otherwise it selects relationships.
+func (e *Expression) SelectsElements() bool {
+	return e.element != nil
+}
+
+// Returns the elements of the model selected by the expression, deployment environments are never selected.
+func (e *Expression) Elements(m *Model) []*Element {
+	selected := make([]*Element, 0)
+	if e.element == nil {
+		return selected
+	}
+	for _, el := range m.Elements {
+		if !el.isEnvironment() && e.element(el) {
+			selected = append(selected, el)
+		}
+	}
+	return selected
+}
+
+// Returns the relationships of the model selected by the expression.
+func (e *Expression) Relationships(m *Model) []*Relationship {
+	selected := make([]*Relationship, 0)
+	if e.relationship == nil {
+		return selected
+	}
+	for _, r := range m.Relationships {
+		if e.relationship(r) {
+			selected = append(selected, r)
+		}
+	}
+	return selected
+}
+
+// ExpressionError is a problem of an expression located at the failing part of it.
+type ExpressionError struct {
+	Message string
+	Range   Range
+	// Code and data of the diagnostic reporting the error
+	Code DiagnosticCode
+	Data []string
+}
+
+func (e *ExpressionError) Error() string {
+	return e.Message
+}
+
+type expressionParser struct {
+	token   *Token
+	model   *Model
+	resolve func(identifier string, rng Range) *Element
+}
+
+// Parses the content of the token as an expression, identifiers are resolved with the given function receiving
+// their location too.
+func ParseExpression(token *Token, model *Model, resolve func(identifier string, rng Range) *Element) (*Expression, *ExpressionError) {
+	p := &expressionParser{token: token, model: model, resolve: resolve}
+	return p.parseOr(token.Content, 0)
+}
+
+func (p *expressionParser) parseOr(text string, offset int) (*Expression, *ExpressionError) {
+	return p.combine(text, offset, "||", p.parseAnd, func(a, b bool) bool { return a || b })
+}
+
+func (p *expressionParser) parseAnd(text string, offset int) (*Expression, *ExpressionError) {
+	return p.combine(text, offset, "&&", p.parseTerm, func(a, b bool) bool { return a && b })
+}
+
+// Splits the text by the operator and combines the parsed parts, which must select the same kind of objects.
+func (p *expressionParser) combine(text string, offset int, operator string, parse func(string, int) (*Expression, *ExpressionError), op func(bool, bool) bool) (*Expression, *ExpressionError) {
+	var result *Expression
+	for _, part := range splitOffsets(text, offset, operator) {
+		expr, err := parse(part.text, part.offset)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = expr
+			continue
+		}
+		if result.SelectsElements() != expr.SelectsElements() {
+			return nil, p.error("Element and relationship expressions cannot be combined", part.text, part.offset)
+		}
+		left, right := result, expr
+		if result.SelectsElements() {
+			result = &Expression{element: func(e *Element) bool { return op(left.element(e), right.element(e)) }}
+		} else {
+			result = &Expression{relationship: func(r *Relationship) bool { return op(left.relationship(r), right.relationship(r)) }}
+		}
+	}
+	return result, nil
+}
+
+func (p *expressionParser) parseTerm(text string, offset int) (*Expression, *ExpressionError) {
+	if text == "" {
+		return nil, p.error("Expected an expression", text, offset)
+	}
+	switch {
+	case text == "*":
+		return &Expression{element: func(*Element) bool { return true }}, nil
+	case strings.HasPrefix(text, "element.") || strings.HasPrefix(text, "element=="):
+		return p.parseElementProperty(text, offset)
+	case strings.HasPrefix(text, "relationship.") || strings.HasPrefix(text, "relationship=="):
+		return p.parseRelationshipProperty(text, offset)
+	case strings.Contains(text, "->"):
+		return p.parseArrow(text, offset)
+	}
+	e, err := p.element(text, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{element: func(el *Element) bool { return el == e }}, nil
+}
+
+func (p *expressionParser) parseElementProperty(text string, offset int) (*Expression, *ExpressionError) {
+	property, operator, value, valueOffset := splitComparison(text, offset)
+	if operator == "" {
+		return nil, p.error("Expected == or != in "+text, text, offset)
+	}
+	var match func(*Element) bool
+	switch {
+	case property == "element":
+		if value == "*" {
+			match = func(*Element) bool { return true }
+			break
+		}
+		e, err := p.element(value, valueOffset)
+		if err != nil {
+			return nil, err
+		}
+		match = func(el *Element) bool { return el == e }
+	case property == "element.type":
+		kind, ok := expressionTypes[strings.ToLower(value)]
+		if !ok {
+			return nil, p.error("Unknown element type "+value, value, valueOffset)
+		}
+		match = func(el *Element) bool { return el.Type == kind }
+	case property == "element.tag":
+		tags := splitTags(value)
+		match = func(el *Element) bool { return hasTags(el.Tags, tags) }
+	case property == "element.technology":
+		match = func(el *Element) bool { return el.Technology == value }
+	case property == "element.parent":
+		parent, err := p.element(value, valueOffset)
+		if err != nil {
+			return nil, err
+		}
+		match = func(el *Element) bool { return el.Parent == parent }
+	case strings.HasPrefix(property, "element.properties[") && strings.HasSuffix(property, "]"):
+		name := strings.TrimSuffix(strings.TrimPrefix(property, "element.properties["), "]")
+		match = func(el *Element) bool { return el.Properties[name] == value }
+	default:
+		return nil, p.error("Unknown property "+property, property, offset)
+	}
+	if operator == "!=" {
+		return &Expression{element: func(el *Element) bool { return !match(el) }}, nil
+	}
+	return &Expression{element: match}, nil
+}
+
+func (p *expressionParser) parseRelationshipProperty(text string, offset int) (*Expression, *ExpressionError) {
+	property, operator, value, valueOffset := splitComparison(text, offset)
+	if operator == "" {
+		return nil, p.error("Expected == or != in "+text, text, offset)
+	}
+	var match func(*Relationship) bool
+	switch {
+	case property == "relationship":
+		if value == "*" {
+			match = func(*Relationship) bool { return true }
+			break
+		}
+		expr, err := p.parseArrow(value, valueOffset)
+		if err != nil {
+			return nil, err
+		}
+		if expr.relationship == nil {
+			return nil, p.error("Expected a relationship", value, valueOffset)
+		}
+		match = expr.relationship
+	case property == "relationship.tag":
+		tags := splitTags(value)
+		match = func(r *Relationship) bool { return hasTags(r.Tags, tags) }
+	case property == "relationship.technology":
+		match = func(r *Relationship) bool { return r.Technology == value }
+	case property == "relationship.source" || property == "relationship.destination":
+		e, err := p.element(value, valueOffset)
+		if err != nil {
+			return nil, err
+		}
+		if property == "relationship.source" {
+			match = func(r *Relationship) bool { return r.Source == e }
+		} else {
+			match = func(r *Relationship) bool { return r.Destination == e }
+		}
+	case strings.HasPrefix(property, "relationship.properties[") && strings.HasSuffix(property, "]"):
+		name := strings.TrimSuffix(strings.TrimPrefix(property, "relationship.properties["), "]")
+		match = func(r *Relationship) bool { return r.Properties[name] == value }
+	default:
+		return nil, p.error("Unknown property "+property, property, offset)
+	}
+	if operator == "!=" {
+		return &Expression{relationship: func(r *Relationship) bool { return !match(r) }}, nil
+	}
+	return &Expression{relationship: match}, nil
+}
+
+// Parses ->e-> (the element with its afferent and efferent couplings), ->e, e-> or a relationship like a->b where
+// either side may be *.
+func (p *expressionParser) parseArrow(text string, offset int) (*Expression, *ExpressionError) {
+	afferent := strings.HasPrefix(text, "->")
+	efferent := strings.HasSuffix(text, "->") && len(text) > 2
+	if afferent || efferent {
+		name, nameOffset := text, offset
+		if afferent {
+			name, nameOffset = name[2:], nameOffset+2
+		}
+		if efferent {
+			name = name[:len(name)-2]
+		}
+		trimmed := strings.TrimLeft(name, " ")
+		nameOffset += len(name) - len(trimmed)
+		name = strings.TrimSpace(trimmed)
+		e, err := p.element(name, nameOffset)
+		if err != nil {
+			return nil, err
+		}
+		return &Expression{element: func(el *Element) bool {
+			if el == e {
+				return true
+			}
+			for _, r := range p.model.Relationships {
+				if afferent && r.Destination == e && r.Source == el || efferent && r.Source == e && r.Destination == el {
+					return true
+				}
+			}
+			return false
+		}}, nil
+	}
+	parts := splitOffsets(text, offset, "->")
+	if len(parts) != 2 {
+		return nil, p.error("Expected a relationship like source->destination", text, offset)
+	}
+	ends := make([]*Element, 2)
+	for i, part := range parts {
+		if part.text == "*" {
+			continue
+		}
+		e, err := p.element(part.text, part.offset)
+		if err != nil {
+			return nil, err
+		}
+		ends[i] = e
+	}
+	source, destination := ends[0], ends[1]
+	return &Expression{relationship: func(r *Relationship) bool {
+		return (source == nil || r.Source == source) && (destination == nil || r.Destination == destination)
+	}}, nil
+}
+
+func (p *expressionParser) element(identifier string, offset int) (*Element, *ExpressionError) {
+	if identifier == "" {
+		return nil, p.error("Expected an identifier", identifier, offset)
+	}
+	e := p.resolve(identifier, p.locate(identifier, offset))
+	if e == nil {
+		err := p.error("Unknown identifier "+identifier, identifier, offset)
+		err.Code, err.Data = CodeUnknownIdentifier, []string{identifier}
+		return nil, err
+	}
+	return e, nil
+}
+
+func (p *expressionParser) error(message string, text string, offset int) *ExpressionError {
+	return &ExpressionError{Message: message, Range: p.locate(text, offset)}
+}
+
+// Locates the part of the expression, offsets are relative to the content of the token.
+func (p *expressionParser) locate(text string, offset int) Range {
+	start := p.token.Location
+	if p.token.Type == TokenString {
+		start.Pos++
+	}
+	start.Pos += len([]rune(p.token.Content[:offset]))
+	end := start
+	end.Pos += len([]rune(text))
+	return Range{Start: start, End: end}
+}
+
+type expressionPart struct {
+	text   string
+	offset int
+}
+
+// Splits the text by the separator keeping the byte offset of each trimmed part.
+func splitOffsets(text string, offset int, separator string) []expressionPart {
+	parts := make([]expressionPart, 0)
+	for {
+		i := strings.Index(text, separator)
+		part := text
+		if i >= 0 {
+			part = text[:i]
+		}
+		trimmed := strings.TrimLeft(part, " ")
+		parts = append(parts, expressionPart{text: strings.TrimSpace(trimmed), offset: offset + len(part) - len(trimmed)})
+		if i < 0 {
+			return parts
+		}
+		text = text[i+len(separator):]
+		offset += i + len(separator)
+	}
+}
+
+// Splits a comparison like element.tag==Database into its property, operator and value.
+func splitComparison(text string, offset int) (string, string, string, int) {
+	for _, operator := range []string{"==", "!="} {
+		if i := strings.Index(text, operator); i >= 0 {
+			value := strings.TrimLeft(text[i+2:], " ")
+			valueOffset := offset + len(text) - len(value)
+			return strings.TrimSpace(text[:i]), operator, strings.TrimSpace(value), valueOffset
+		}
+	}
+	return text, "", "", offset
+}
+
+// Reports whether every wanted tag is among the tags, tags are case-insensitive.
+func hasTags(tags []string, wanted []string) bool {
+	for _, w := range wanted {
+		if !slices.ContainsFunc(tags, func(t string) bool { return strings.EqualFold(t, w) }) {
+			return false
+		}
+	}
+	return len(wanted) > 0
+}
@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpression(t *testing.T) {
+	sut := NewTestAnalyser("workspace {\n!impliedRelationships false\nmodel {\nuser = person \"User\"\nss = softwareSystem \"System\" {\napi = container \"API\" \"\" \"Go\" \"Public\"\ndb = container \"DB\" \"\" \"Postgres\" \"Database\"\n}\nuser -> api \"Uses\" \"HTTPS\" \"Sync\"\napi -> db \"Reads\" \"SQL\" \"Async\"\n}\nviews {\n}\n}")
+	ws, _, diags := sut.Analyse()
+	assert.Empty(t, diags)
+	model := ws.Model
+	parse := func(expression string) (*Expression, *ExpressionError) {
+		token := &Token{Type: TokenString, Content: expression, Location: Location{Source: "test.dsl", Line: 1, Pos: 10}}
+		return ParseExpression(token, model, func(identifier string, _ Range) *Element { return model.Element(identifier) })
+	}
+	names := func(elements []*Element) []string {
+		result := make([]string, 0)
+		for _, e := range elements {
+			result = append(result, e.Name)
+		}
+		return result
+	}
+	descriptions := func(relationships []*Relationship) []string {
+		result := make([]string, 0)
+		for _, r := range relationships {
+			result = append(result, r.Description)
+		}
+		return result
+	}
+	for _, c := range []struct {
+		expression string
+		elements   []string
+	}{
+		{"*", []string{"User", "System", "API", "DB"}},
+		{"api", []string{"API"}},
+		{"element==db", []string{"DB"}},
+		{"element.type==Container", []string{"API", "DB"}},
+		{"element.tag==Database", []string{"DB"}},
+		{"element.tag!=Database", []string{"User", "System", "API"}},
+		{"element.technology==Go", []string{"API"}},
+		{"element.parent==ss", []string{"API", "DB"}},
+		{"->api->", []string{"User", "API", "DB"}},
+		{"->api", []string{"User", "API"}},
+		{"api->", []string{"API", "DB"}},
+		{"element.type==Container && element.tag==Public", []string{"API"}},
+		{"element.type==Person || element.tag==Database", []string{"User", "DB"}},
+	} {
+		t.Run(c.expression, func(t *testing.T) {
+			expr, err := parse(c.expression)
+			if assert.Nil(t, err) && assert.True(t, expr.SelectsElements()) {
+				assert.Equal(t, c.elements, names(expr.Elements(model)))
+			}
+		})
+	}
+	for _, c := range []struct {
+		expression    string
+		relationships []string
+	}{
+		{"*->*", []string{"Uses", "Reads"}},
+		{"user->*", []string{"Uses"}},
+		{"*->db", []string{"Reads"}},
+		{"relationship==*", []string{"Uses", "Reads"}},
+		{"relationship==api->db", []string{"Reads"}},
+		{"relationship.tag==Async", []string{"Reads"}},
+		{"relationship.technology==HTTPS", []string{"Uses"}},
+		{"relationship.source==api", []string{"Reads"}},
+		{"relationship.destination==api", []string{"Uses"}},
+		{"relationship.tag!=Async && relationship.source==user", []string{"Uses"}},
+	} {
+		t.Run(c.expression, func(t *testing.T) {
+			expr, err := parse(c.expression)
+			if assert.Nil(t, err) && assert.False(t, expr.SelectsElements()) {
+				assert.Equal(t, c.relationships, descriptions(expr.Relationships(model)))
+			}
+		})
+	}
+	for _, c := range []struct {
+		expression string
+		message    string
+		start, end int
+	}{
+		{"element.type==Database", "Unknown element type Database", 25, 33},
+		{"element.tag==A && missing", "Unknown identifier missing", 29, 36},
+		{"->missing->", "Unknown identifier missing", 13, 20},
+		{"user -> missing", "Unknown identifier missing", 19, 26},
+		{"element.colour==red", "Unknown property element.colour", 11, 25},
+		{"element.type==Person && *->*", "Element and relationship expressions cannot be combined", 35, 39},
+		{"element.tag==A &&", "Expected an expression", 28, 28},
+	} {
+		t.Run("reports "+c.expression, func(t *testing.T) {
+			_, err := parse(c.expression)
+			if assert.NotNil(t, err) {
+				assert.Equal(t, c.message, err.Message)
+				assert.Equal(t, Range{Start: Location{Source: "test.dsl", Line: 1, Pos: c.start}, End: Location{Source: "test.dsl", Line: 1, Pos: c.end}}, err.Range)
+			}
+		})
+	}
+}
+
+func TestExpressionWithDeploymentEnvironments(t *testing.T) {
+	sut := NewTestAnalyser("workspace {\nmodel {\nss = softwareSystem \"System\"\nlive = deploymentEnvironment \"Live\" {\nserver = deploymentNode \"Server\" {\nsoftwareSystemInstance ss\n}\n}\n}\nviews {\n}\n}")
+	ws, _, diags := sut.Analyse()
+	assert.Empty(t, diags)
+	model := ws.Model
+	for _, expression := range []string{"*", "live", "element.parent==live || element==live"} {
+		t.Run("environments are not selected by "+expression, func(t *testing.T) {
+			token := &Token{Type: TokenString, Content: expression, Location: Location{Source: "test.dsl", Line: 1, Pos: 10}}
+			expr, err := ParseExpression(token, model, func(identifier string, _ Range) *Element { return model.Element(identifier) })
+			if assert.Nil(t, err) {
+				for _, e := range expr.Elements(model) {
+					assert.NotEqual(t, "deploymentEnvironment", e.Type)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package parser
+
+import "strings"
+
+// Strategies of !impliedRelationships, which create relationships between the parents of the source and the
+// destination of every relationship.
+const (
+	ImpliedRelationshipsNone            = "DefaultImpliedRelationshipsStrategy"
+	ImpliedRelationshipsUnlessAnyExists = "CreateImpliedRelationshipsUnlessAnyRelationshipExistsStrategy"
+	ImpliedRelationshipsUnlessSameExist = "CreateImpliedRelationshipsUnlessSameRelationshipExistsStrategy"
+)
+
+// Package of the strategies, which may prefix their names.
+const strategyPackage = "com.structurizr.model."
+
+// Returns the strategy of an !impliedRelationships option, false when the option is unknown.
+func impliedRelationshipsStrategy(option string) (string, bool) {
+	switch strings.TrimPrefix(option, strategyPackage) {
+	case "true", ImpliedRelationshipsUnlessAnyExists:
+		return ImpliedRelationshipsUnlessAnyExists, true
+	case "false", ImpliedRelationshipsNone:
+		return ImpliedRelationshipsNone, true
+	case ImpliedRelationshipsUnlessSameExist:
+		return ImpliedRelationshipsUnlessSameExist, true
+	}
+	return "", false
+}
+
+// Creates the relationships implied by the relationship between every ancestor of its source and destination as
+// Structurizr does, e.g. a component using a container implies its container and software system using it too.
+func (m *Model) addImpliedRelationships(r *Relationship, strategy string) {
+	if strategy == ImpliedRelationshipsNone {
+		return
+	}
+	for source := r.Source; source != nil; source = impliedParent(source) {
+		for destination := r.Destination; destination != nil; destination = impliedParent(destination) {
+			if source == r.Source && destination == r.Destination || !impliedRelationshipAllowed(source, destination) {
+				continue
+			}
+			if m.hasRelationship(source, destination, strategy == ImpliedRelationshipsUnlessSameExist, r.Description) {
+				continue
+			}
+			m.addRelationship(&Relationship{
+				Details: Details{
+					Description: r.Description,
+					Technology:  r.Technology,
+					Tags:        []string{"Relationship"},
+					Properties:  make(map[string]string),
+				},
+				Source:             source,
+				Destination:        destination,
+				Definition:         r.Definition,
+				LinkedRelationship: r,
+			})
+		}
+	}
+}
+
+// Deployment environments are not elements, relationships are not implied between them.
+func impliedParent(e *Element) *Element {
+	if e.Parent == nil || e.Parent.isEnvironment() {
+		return nil
+	}
+	return e.Parent
+}
+
+// Relationships are not implied between an element and its own ancestors.
+func impliedRelationshipAllowed(source, destination *Element) bool {
+	return source != destination && !isAncestor(source, destination) && !isAncestor(destination, source)
+}
+
+func isAncestor(ancestor, e *Element) bool {
+	for p := e.Parent; p != nil; p = p.Parent {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether a relationship exists from the source to the destination, optionally with the given description.
+func (m *Model) hasRelationship(source, destination *Element, sameDescription bool, description string) bool {
+	for _, r := range m.Relationships {
+		if r.Source == source && r.Destination == destination && (!sameDescription || r.Description == description) {
+			return true
+		}
+	}
+	return false
+}
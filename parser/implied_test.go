@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpliedRelationships(t *testing.T) {
+	relationships := func(t *testing.T, directive string, model string) []string {
+		sut := NewTestAnalyser("workspace {\n" + directive + "\nmodel {\nuser = person \"User\"\nss = softwareSystem \"System\" {\napi = container \"API\" {\ncontroller = component \"Controller\"\n}\ndb = container \"DB\"\n}\n" + model + "\n}\nviews {\n}\n}")
+		ws, _, diags := sut.Analyse()
+		assert.Empty(t, diags)
+		result := make([]string, 0)
+		for _, r := range ws.Model.Relationships {
+			result = append(result, r.Source.Name+" -> "+r.Destination.Name+" "+r.Description)
+		}
+		return result
+	}
+	t.Run("relationships are implied between the parents by default", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"User -> Controller Uses",
+			"User -> API Uses",
+			"User -> System Uses",
+		}, relationships(t, "", "user -> controller \"Uses\""))
+	})
+	t.Run("relationships are not implied between an element and its parents", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"Controller -> DB Reads",
+			"API -> DB Reads",
+		}, relationships(t, "", "controller -> db \"Reads\""))
+	})
+	t.Run("false disables implied relationships", func(t *testing.T) {
+		assert.Equal(t, []string{"User -> Controller Uses"}, relationships(t, "!impliedRelationships false", "user -> controller \"Uses\""))
+	})
+	t.Run("existing relationships prevent implied ones", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"User -> System Calls",
+			"User -> Controller Uses",
+			"User -> API Uses",
+		}, relationships(t, "!impliedRelationships true", "user -> ss \"Calls\"\nuser -> controller \"Uses\""))
+	})
+	t.Run("only the same relationships prevent implied ones with the same relationship strategy", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"User -> System Calls",
+			"User -> Controller Uses",
+			"User -> API Uses",
+			"User -> System Uses",
+		}, relationships(t, "!impliedRelationships com.structurizr.model.CreateImpliedRelationshipsUnlessSameRelationshipExistsStrategy", "user -> ss \"Calls\"\nuser -> controller \"Uses\""))
+	})
+	t.Run("the strategy applies to the relationships following the directive", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"User -> API Uses",
+			"User -> System Uses",
+			"User -> DB Reads",
+		}, relationships(t, "", "user -> api \"Uses\"\n!impliedRelationships false\nuser -> db \"Reads\""))
+	})
+	t.Run("implied relationships are linked to their relationship", func(t *testing.T) {
+		sut := NewTestAnalyser("workspace {\nmodel {\nuser = person \"User\"\nss = softwareSystem \"System\" {\napi = container \"API\"\n}\nuser -> api\n}\nviews {\n}\n}")
+		ws, _, _ := sut.Analyse()
+		if assert.Equal(t, 2, len(ws.Model.Relationships)) {
+			assert.Nil(t, ws.Model.Relationships[0].LinkedRelationship)
+			assert.Equal(t, ws.Model.Relationships[0], ws.Model.Relationships[1].LinkedRelationship)
+			assert.Equal(t, ImpliedRelationshipsUnlessAnyExists, ws.Model.ImpliedRelationships)
+		}
+	})
+	t.Run("unknown strategies are reported", func(t *testing.T) {
+		sut := NewTestAnalyser("workspace {\nmodel {\n!impliedRelationships maybe\n}\nviews {\n}\n}")
+		_, _, diags := sut.Analyse()
+		if assert.Equal(t, 1, len(diags)) {
+			assert.Equal(t, "Unknown implied relationships strategy maybe", diags[0].Message)
+			assert.Equal(t, Location{Source: "test.dsl", Line: 2, Pos: 22}, diags[0].Location)
+		}
+	})
+}
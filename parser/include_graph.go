@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+// IncludeGraph records which files are included by the !include directives of a workspace, starting from the root
+// file and following the includes transitively.
+type IncludeGraph struct {
+	Root     string
+	files    []string
+	includes map[string][]*Include
+	base     *IncludeGraph
+	// documentation files and directories of !docs and !adrs
+	documentation []string
+}
+
+// Include is a single !include directive, a directory include resolves to every .dsl file inside it.
+type Include struct {
+	Directive Token
+	Path      string
+	Files     []string
+}
+
+func newIncludeGraph(root string) *IncludeGraph {
+	return &IncludeGraph{Root: root, files: []string{root}, includes: make(map[string][]*Include)}
+}
+
+// Returns the includes directly declared in the given file.
+func (g *IncludeGraph) Includes(source string) []*Include {
+	return g.includes[source]
+}
+
+// Returns every file of the graph in the order they were included, starting with the root.
+func (g *IncludeGraph) Files() []string {
+	return g.files
+}
+
+// Reports whether the file is the root or included by it.
+func (g *IncludeGraph) Contains(file string) bool {
+	return slices.Contains(g.files, file)
+}
+
+// Reports whether the analysis depends on the path, which is an included file, an included directory, a file inside
+// an included directory, a file which failed to be included, documentation or a file of the extended workspace.
+func (g *IncludeGraph) DependsOn(path string) bool {
+	if g.Contains(path) || (g.base != nil && g.base.DependsOn(path)) {
+		return true
+	}
+	for _, doc := range g.documentation {
+		if doc == path || doc == filepath.Dir(path) {
+			return true
+		}
+	}
+	for _, includes := range g.includes {
+		for _, in := range includes {
+			if in.Path == path || in.Path == filepath.Dir(path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the files which directly include the given file.
+func (g *IncludeGraph) IncludedBy(file string) []string {
+	sources := make([]string, 0)
+	for _, source := range g.files {
+		for _, in := range g.includes[source] {
+			if slices.Contains(in.Files, file) && !slices.Contains(sources, source) {
+				sources = append(sources, source)
+			}
+		}
+	}
+	return sources
+}
+
+// Returns the documentation files and directories the workspace and the extended one read.
+func (g *IncludeGraph) Documentation() []string {
+	if g.base == nil {
+		return g.documentation
+	}
+	return append(slices.Clone(g.documentation), g.base.Documentation()...)
+}
+
+// Returns the graph of the workspace extended by the root, nil if it does not extend any.
+func (g *IncludeGraph) Base() *IncludeGraph {
+	return g.base
+}
+
+func (g *IncludeGraph) addDocumentation(path string) {
+	if !slices.Contains(g.documentation, path) {
+		g.documentation = append(g.documentation, path)
+	}
+}
+
+func (g *IncludeGraph) add(source string, in *Include) {
+	g.includes[source] = append(g.includes[source], in)
+}
+
+type includeResolver struct {
+	graph       *IncludeGraph
+	includer    Includer
+	diagnostics []*Diagnostic
+}
+
+// Replaces the !include directives with the tokens of the included files. The stack holds the chain of files
+// currently being included to detect cycles.
+func (r *includeResolver) expand(tokens []Token, source string, stack []string) []Token {
+	result := make([]Token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Content != "!include" || tokens[i].Type != TokenKeyword {
+			result = append(result, tokens[i])
+			continue
+		}
+		directive := tokens[i]
+		if i+1 >= len(tokens) || (tokens[i+1].Type != TokenKeyword && tokens[i+1].Type != TokenString) {
+			r.addDiagnostic("Expected a file or directory to include", directive.Location)
+			result = append(result, directive)
+			continue
+		}
+		path := tokens[i+1].Content
+		fullpath := ResolvePath(source, path)
+		result = append(result, directive, tokens[i+1])
+		result = append(result, Token{Type: TokenNewline, Content: "", Location: directive.Location, End: directive.Location})
+		i++
+		include := &Include{Directive: directive, Path: fullpath}
+		r.graph.add(source, include)
+		files, err := r.includer.Include(fullpath)
+		if err != nil {
+			logger.Printf("Error during include %s on absolute path %s cause: %s", path, fullpath, err)
+			r.addDiagnostic(fmt.Sprintf("Failed to include %s: %s", path, err), directive.Location)
+			continue
+		}
+		for j, file := range files {
+			include.Files = append(include.Files, file.Path)
+			if slices.Contains(stack, file.Path) {
+				r.addDiagnostic(fmt.Sprintf("Cyclic include of %s", file.Path), directive.Location)
+				continue
+			}
+			if !r.graph.Contains(file.Path) {
+				r.graph.files = append(r.graph.files, file.Path)
+			}
+			included, _ := tokenize(file.Path, file.Content)
+			result = append(result, r.expand(included, file.Path, append(slices.Clone(stack), file.Path))...)
+			if j < len(files)-1 {
+				// statements of subsequent files in a directory must not be joined
+				result = append(result, Token{Type: TokenNewline, Content: "", Location: directive.Location, End: directive.Location})
+			}
+		}
+	}
+	return result
+}
+
+func (r *includeResolver) addDiagnostic(message string, location Location) {
+	r.diagnostics = append(r.diagnostics, &Diagnostic{Severity: DiagnosticError, Message: message, Location: location})
+}
@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncludeGraph(t *testing.T) {
+	fake := &FakeIncluder{}
+	t.Run("records the included files", func(t *testing.T) {
+		_, graph, diagnostics := LexWithIncludes("/ws/workspace.dsl", "!include test.dsl\n!include file.dsl", fake)
+		assert.Empty(t, diagnostics)
+		assert.Equal(t, []string{"/ws/workspace.dsl", "/ws/test.dsl", "/ws/file.dsl"}, graph.Files())
+		if assert.Equal(t, 2, len(graph.Includes("/ws/workspace.dsl"))) {
+			include := graph.Includes("/ws/workspace.dsl")[0]
+			assert.Equal(t, "/ws/test.dsl", include.Path)
+			assert.Equal(t, []string{"/ws/test.dsl"}, include.Files)
+			assert.Equal(t, Location{Source: "/ws/workspace.dsl", Line: 0, Pos: 0}, include.Directive.Location)
+		}
+		assert.Equal(t, []string{"/ws/workspace.dsl"}, graph.IncludedBy("/ws/file.dsl"))
+		assert.True(t, graph.Contains("/ws/file.dsl"))
+	})
+	t.Run("depends on included directories and missing files", func(t *testing.T) {
+		_, graph, _ := LexWithIncludes("/ws/workspace.dsl", "!include dir\n!include missing.dsl", fake)
+		assert.True(t, graph.DependsOn("/ws/dir/first.dsl"))
+		assert.True(t, graph.DependsOn("/ws/dir/new.dsl"))
+		assert.True(t, graph.DependsOn("/ws/missing.dsl"))
+		assert.False(t, graph.DependsOn("/ws/other.dsl"))
+	})
+	t.Run("directory includes map tokens to each file", func(t *testing.T) {
+		tokens, graph, diagnostics := LexWithIncludes("/ws/workspace.dsl", "!include dir", fake)
+		assert.Empty(t, diagnostics)
+		assert.Equal(t, []string{"/ws/workspace.dsl", "/ws/dir/first.dsl", "/ws/dir/last.dsl"}, graph.Files())
+		assert.Equal(t, "first", tokens[3].Content)
+		assert.Equal(t, "/ws/dir/first.dsl", tokens[3].Location.Source)
+		assert.Equal(t, TokenNewline, tokens[4].Type)
+		assert.Equal(t, "last", tokens[5].Content)
+		assert.Equal(t, "/ws/dir/last.dsl", tokens[5].Location.Source)
+	})
+	t.Run("cyclic includes are reported on the directive", func(t *testing.T) {
+		tokens, _, diagnostics := LexWithIncludes("/ws/self.dsl", "workspace\n!include self.dsl", fake)
+		if assert.Equal(t, 1, len(diagnostics)) {
+			assert.Equal(t, "Cyclic include of /ws/self.dsl", diagnostics[0].Message)
+			assert.Equal(t, Location{Source: "/ws/self.dsl", Line: 1, Pos: 0}, diagnostics[0].Location)
+		}
+		assert.Equal(t, TokenEof, tokens[len(tokens)-1].Type)
+	})
+	t.Run("indirect cycles are reported in the including file", func(t *testing.T) {
+		_, graph, diagnostics := LexWithIncludes("/ws/workspace.dsl", "!include self.dsl", fake)
+		if assert.Equal(t, 1, len(diagnostics)) {
+			assert.Equal(t, "/ws/self.dsl", diagnostics[0].Location.Source)
+		}
+		assert.Equal(t, []string{"/ws/workspace.dsl", "/ws/self.dsl"}, graph.IncludedBy("/ws/self.dsl"))
+	})
+	t.Run("failing includes do not stop lexing", func(t *testing.T) {
+		tokens, _, diagnostics := LexWithIncludes("/ws/workspace.dsl", "!include missing.dsl\nworkspace", fake)
+		if assert.Equal(t, 1, len(diagnostics)) {
+			assert.Equal(t, "Failed to include missing.dsl: failed to open /ws/missing.dsl", diagnostics[0].Message)
+		}
+		assert.Equal(t, "workspace", tokens[4].Content)
+	})
+	t.Run("include without a path is reported", func(t *testing.T) {
+		_, _, diagnostics := LexWithIncludes("/ws/workspace.dsl", "!include\nworkspace", fake)
+		if assert.Equal(t, 1, len(diagnostics)) {
+			assert.Equal(t, "Expected a file or directory to include", diagnostics[0].Message)
+		}
+	})
+}
@@ -5,57 +5,111 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
 type FSIncluder struct {
 }
 
+// Includer resolves the files of !include directives, implementations may serve them from other places than the
+// disk, e.g. from the unsaved buffers of an editor.
 type Includer interface {
 	// Returns the content of a file or each .dsl file in a directory.
 	// Requires an absolute path.
-	include(included string) (string, error)
+	Include(included string) ([]IncludedFile, error)
+	// Returns the content of a documentation file or each Markdown and AsciiDoc file in a documentation directory,
+	// e.g. the decisions of !adrs. Requires an absolute path.
+	Documentation(path string) ([]IncludedFile, error)
+}
+
+// IncludedFile is the content of a single file returned by an Includer.
+type IncludedFile struct {
+	Path    string
+	Content string
 }
 
 type FakeIncluder struct {
 }
 
-func (f *FakeIncluder) include(included string) (string, error) {
+func (f *FakeIncluder) Include(included string) ([]IncludedFile, error) {
 	if strings.HasSuffix(included, "test.dsl") {
-		return "user \"Person\"", nil
+		return []IncludedFile{{Path: included, Content: "user \"Person\""}}, nil
 	}
 	if strings.HasSuffix(included, "file.dsl") {
-		return "a = workspace \"test\"", nil
+		return []IncludedFile{{Path: included, Content: "a = workspace \"test\""}}, nil
+	}
+	if strings.HasSuffix(included, "self.dsl") {
+		return []IncludedFile{{Path: included, Content: "!include self.dsl"}}, nil
+	}
+	if strings.HasSuffix(included, "base.dsl") {
+		return []IncludedFile{{Path: included, Content: "workspace {\nmodel {\nuser = person \"User\"\n}\nviews {\n}\n}"}}, nil
+	}
+	if strings.HasSuffix(included, "platform.dsl") {
+		return []IncludedFile{{Path: included, Content: "workspace {\nmodel {\narchetypes {\napplication = container\n}\nss = softwareSystem \"System\"\n}\nviews {\nsystemContext ss \"Context\" {\ninclude *\n}\n}\n}"}}, nil
+	}
+	if strings.HasSuffix(included, "derived.dsl") {
+		return []IncludedFile{{Path: included, Content: "workspace extends base.dsl {\nmodel {\n}\n}"}}, nil
+	}
+	if strings.HasSuffix(included, "dir") {
+		return []IncludedFile{{Path: included + "/first.dsl", Content: "first"}, {Path: included + "/last.dsl", Content: "last"}}, nil
+	}
+	return nil, fmt.Errorf("failed to open %s", included)
+}
+
+func (f *FakeIncluder) Documentation(path string) ([]IncludedFile, error) {
+	if strings.HasSuffix(path, "some/path") {
+		return []IncludedFile{{Path: path + "/0001-record-architecture-decisions.md", Content: "# 1. Record architecture decisions\n\n## Status\n\nAccepted\n"}}, nil
 	}
-	return "", fmt.Errorf("failed to open %s", included)
+	return nil, fmt.Errorf("failed to stat path: %s does not exist", path)
 }
 
+// Returns an includer reading local files and fetching remote ones with the default options.
 func NewIncluder() Includer {
-	return &FSIncluder{}
+	return NewRemoteIncluder(&FSIncluder{}, DefaultRemoteOptions())
 }
 
-func (f *FSIncluder) include(included string) (string, error) {
+func (f *FSIncluder) Include(included string) ([]IncludedFile, error) {
 	// Get file info for the given path
 	info, err := os.Stat(included)
 	if err != nil {
-		return "", fmt.Errorf("failed to stat path: %w", err)
+		return nil, fmt.Errorf("failed to stat path: %w", err)
 	}
 
 	// Check if the path is a directory
 	if info.IsDir() {
-		return readDir(included)
-	} else {
-		return readFile(included)
+		return readDir(included, ".dsl")
+	}
+	content, err := readFile(included)
+	if err != nil {
+		return nil, err
+	}
+	return []IncludedFile{{Path: included, Content: content}}, nil
+}
+
+func (f *FSIncluder) Documentation(path string) ([]IncludedFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+	if info.IsDir() {
+		return readDir(path, ".md", ".adoc")
+	}
+	content, err := readFile(path)
+	if err != nil {
+		return nil, err
 	}
+	return []IncludedFile{{Path: path, Content: content}}, nil
 }
 
-func readDir(path string) (string, error) {
+// Reads every file of the directory with one of the extensions ordered by their name, subdirectories are skipped.
+func readDir(path string, extensions ...string) ([]IncludedFile, error) {
 	// Read all files in the directory
 	entries, err := os.ReadDir(path)
 	if err != nil {
-		return "", fmt.Errorf("failed to read directory: %w", err)
+		return nil, fmt.Errorf("failed to read directory: %w", err)
 	}
-	var sb strings.Builder
+	files := make([]IncludedFile, 0)
 	// Iterate over files and process .dsl files
 	for _, entry := range entries {
 		if entry.IsDir() {
@@ -63,18 +117,18 @@ func readDir(path string) (string, error) {
 		}
 
 		// Check file extension
-		if strings.HasSuffix(entry.Name(), ".dsl") {
+		if slices.Contains(extensions, filepath.Ext(entry.Name())) {
 			fullPath := filepath.Join(path, entry.Name())
 
 			// Open and read the .dsl file
 			content, err := readFile(fullPath)
 			if err != nil {
-				return "", fmt.Errorf("failed to read file: %w", err)
+				return nil, fmt.Errorf("failed to read file: %w", err)
 			}
-			sb.WriteString(content)
+			files = append(files, IncludedFile{Path: fullPath, Content: content})
 		}
 	}
-	return sb.String(), nil
+	return files, nil
 }
 
 func readFile(path string) (string, error) {
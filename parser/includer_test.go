@@ -12,29 +12,32 @@ func TestIncluder(t *testing.T) {
 	i := NewIncluder()
 	cwd, _ := os.Getwd()
 	t.Run("it should return file content in the same directory", func(t *testing.T) {
-		content, err := i.include(filepath.Join(cwd, "included.dsl"))
+		files, err := i.Include(filepath.Join(cwd, "included.dsl"))
 		assert.NoError(t, err)
-		assert.Equal(t, "person \"User\"\n", content)
+		assert.Equal(t, []IncludedFile{{Path: filepath.Join(cwd, "included.dsl"), Content: "person \"User\"\n"}}, files)
 	})
 	t.Run("it should work with absolute source path", func(t *testing.T) {
-		content, err := i.include(filepath.Join(cwd, "included.dsl"))
+		files, err := i.Include(filepath.Join(cwd, "included.dsl"))
 		assert.NoError(t, err)
-		assert.Equal(t, "person \"User\"\n", content)
+		assert.Equal(t, []IncludedFile{{Path: filepath.Join(cwd, "included.dsl"), Content: "person \"User\"\n"}}, files)
 	})
 
 	t.Run("it should work with URI", func(t *testing.T) {
-		content, err := i.include(filepath.Join(cwd, "included.dsl"))
+		files, err := i.Include(filepath.Join(cwd, "included.dsl"))
 		assert.NoError(t, err)
-		assert.Equal(t, "person \"User\"\n", content)
+		assert.Equal(t, []IncludedFile{{Path: filepath.Join(cwd, "included.dsl"), Content: "person \"User\"\n"}}, files)
 	})
 	t.Run("it should work with directories", func(t *testing.T) {
-		content, err := i.include(filepath.Join(cwd, "included"))
+		files, err := i.Include(filepath.Join(cwd, "included"))
 		assert.NoError(t, err)
-		assert.Equal(t, "first\nlast\n", content)
+		assert.Equal(t, []IncludedFile{
+			{Path: filepath.Join(cwd, "included", "first.dsl"), Content: "first\n"},
+			{Path: filepath.Join(cwd, "included", "last.dsl"), Content: "last\n"},
+		}, files)
 	})
 
 	t.Run("it should return error when failing to read", func(t *testing.T) {
-		_, err := i.include("nonexistent")
+		_, err := i.Include("nonexistent")
 		assert.Error(t, err)
 	})
 }
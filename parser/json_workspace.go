@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Property holding the DSL identifier of elements and relationships in workspaces exported to JSON.
+const identifierProperty = "structurizr.dsl.identifier"
+
+type jsonWorkspace struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Model       struct {
+		People          []*jsonElement `json:"people"`
+		SoftwareSystems []*jsonElement `json:"softwareSystems"`
+		DeploymentNodes []*jsonElement `json:"deploymentNodes"`
+	} `json:"model"`
+}
+
+type jsonElement struct {
+	ID                      string              `json:"id"`
+	Name                    string              `json:"name"`
+	Description             string              `json:"description"`
+	Technology              string              `json:"technology"`
+	URL                     string              `json:"url"`
+	Tags                    string              `json:"tags"`
+	Properties              map[string]string   `json:"properties"`
+	Perspectives            []Perspective       `json:"perspectives"`
+	Relationships           []*jsonRelationship `json:"relationships"`
+	Containers              []*jsonElement      `json:"containers"`
+	Components              []*jsonElement      `json:"components"`
+	Children                []*jsonElement      `json:"children"`
+	InfrastructureNodes     []*jsonElement      `json:"infrastructureNodes"`
+	SoftwareSystemInstances []*jsonElement      `json:"softwareSystemInstances"`
+	ContainerInstances      []*jsonElement      `json:"containerInstances"`
+}
+
+type jsonRelationship struct {
+	ID            string            `json:"id"`
+	SourceID      string            `json:"sourceId"`
+	DestinationID string            `json:"destinationId"`
+	Description   string            `json:"description"`
+	Technology    string            `json:"technology"`
+	URL           string            `json:"url"`
+	Tags          string            `json:"tags"`
+	Properties    map[string]string `json:"properties"`
+	Perspectives  []Perspective     `json:"perspectives"`
+}
+
+// Reads the model of a workspace exported to JSON. Elements are identified by the DSL identifiers stored in their
+// properties, definitions point to their id in the JSON file.
+func workspaceFromJSON(path, content string) (*Workspace, error) {
+	var ws jsonWorkspace
+	if err := json.Unmarshal([]byte(content), &ws); err != nil {
+		return nil, fmt.Errorf("invalid JSON workspace: %w", err)
+	}
+	r := &jsonReader{path: path, content: content, model: newModel(), ids: make(map[string]*Element)}
+	r.readElements("person", ws.Model.People, nil)
+	r.readElements("softwareSystem", ws.Model.SoftwareSystems, nil)
+	r.readElements("deploymentNode", ws.Model.DeploymentNodes, nil)
+	for _, rel := range r.relationships {
+		source, destination := r.ids[rel.SourceID], r.ids[rel.DestinationID]
+		if source == nil || destination == nil {
+			continue
+		}
+		r.model.addRelationship(&Relationship{
+			Identifier:  rel.Properties[identifierProperty],
+			Source:      source,
+			Destination: destination,
+			Details:     Details{Description: rel.Description, Technology: rel.Technology, URL: rel.URL, Tags: splitTags(rel.Tags), Properties: rel.Properties, Perspectives: rel.Perspectives},
+			Definition:  r.definition(rel.ID),
+		})
+	}
+	return &Workspace{Name: ws.Name, Description: ws.Description, Model: r.model, views: &ViewSet{}}, nil
+}
+
+type jsonReader struct {
+	path          string
+	content       string
+	model         *Model
+	ids           map[string]*Element
+	relationships []*jsonRelationship
+}
+
+func (r *jsonReader) readElements(kind string, elements []*jsonElement, parent *Element) {
+	for _, je := range elements {
+		e := &Element{
+			Identifier: je.Properties[identifierProperty],
+			Type:       kind,
+			Name:       je.Name,
+			Details:    Details{Description: je.Description, Technology: je.Technology, URL: je.URL, Tags: splitTags(je.Tags), Properties: je.Properties, Perspectives: je.Perspectives},
+			Parent:     parent,
+			Definition: r.definition(je.ID),
+		}
+		r.ids[je.ID] = e
+		r.model.addElement(e)
+		r.relationships = append(r.relationships, je.Relationships...)
+		r.readElements("container", je.Containers, e)
+		r.readElements("component", je.Components, e)
+		r.readElements("deploymentNode", je.Children, e)
+		r.readElements("infrastructureNode", je.InfrastructureNodes, e)
+		r.readElements("softwareSystemInstance", je.SoftwareSystemInstances, e)
+		r.readElements("containerInstance", je.ContainerInstances, e)
+	}
+}
+
+// Locates the id of an element or a relationship in the JSON file.
+func (r *jsonReader) definition(id string) Range {
+	start := Location{Source: r.path}
+	pattern := regexp.MustCompile(`"id"\s*:\s*"` + regexp.QuoteMeta(id) + `"`)
+	if loc := pattern.FindStringIndex(r.content); loc != nil {
+		before := []rune(r.content[:loc[0]])
+		start.Line = strings.Count(string(before), "\n")
+		start.Pos = len(before) - 1 - lastIndex(before, '\n')
+	}
+	end := start
+	end.Pos += 4
+	return Range{Start: start, End: end}
+}
+
+func lastIndex(runes []rune, r rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONWorkspace(t *testing.T) {
+	content := `{
+  "name": "Base",
+  "model": {
+    "people": [{
+      "id": "1",
+      "name": "User",
+      "properties": {"structurizr.dsl.identifier": "user"},
+      "relationships": [{"id": "3", "sourceId": "1", "destinationId": "2", "description": "Uses"}]
+    }],
+    "softwareSystems": [{
+      "id": "2",
+      "name": "System",
+      "tags": "Element,Software System",
+      "url": "https://example.com",
+      "perspectives": [{"name": "Security", "description": "TLS"}],
+      "containers": [{"id": "4", "name": "API", "properties": {"structurizr.dsl.identifier": "ss.api"}}]
+    }]
+  }
+}`
+	t.Run("elements are identified by their DSL identifier", func(t *testing.T) {
+		ws, err := workspaceFromJSON("base.json", content)
+		if assert.Nil(t, err) {
+			assert.Equal(t, "Base", ws.Name)
+			assert.Equal(t, 3, len(ws.Model.Elements))
+			user := ws.Model.Element("user")
+			if assert.NotNil(t, user) {
+				assert.Equal(t, "person", user.Type)
+				assert.Equal(t, Location{Source: "base.json", Line: 4, Pos: 6}, user.Definition.Start)
+			}
+			system := ws.Model.ElementByCanonicalName("SoftwareSystem://System")
+			if assert.NotNil(t, system) {
+				assert.Equal(t, "https://example.com", system.URL)
+				assert.Equal(t, []Perspective{{Name: "Security", Description: "TLS"}}, system.Perspectives)
+			}
+			api := ws.Model.Element("ss.api")
+			if assert.NotNil(t, api) {
+				assert.Equal(t, "System", api.Parent.Name)
+			}
+		}
+	})
+	t.Run("relationships connect elements by their id", func(t *testing.T) {
+		ws, _ := workspaceFromJSON("base.json", content)
+		if assert.Equal(t, 1, len(ws.Model.Relationships)) {
+			assert.Equal(t, "System", ws.Model.Relationships[0].Destination.Name)
+			assert.Equal(t, "Uses", ws.Model.Relationships[0].Description)
+		}
+	})
+	t.Run("invalid JSON is an error", func(t *testing.T) {
+		_, err := workspaceFromJSON("base.json", "{")
+		assert.NotNil(t, err)
+	})
+}
@@ -2,17 +2,19 @@ package parser
 
 import (
 	"bufio"
+	"errors"
 	"log"
 	"os"
-	"path/filepath"
 	"strings"
 	"unicode"
 )
 
 type Token struct {
 	Type       TokenType
+	Role       TokenRole
 	Content    string
 	Location   Location
+	End        Location
 	Terminated bool
 }
 
@@ -21,22 +23,24 @@ type Location struct {
 	Line   int
 	Pos    int
 }
+
+// Before reports whether the location precedes the other one in the same file.
+func (l Location) Before(other Location) bool {
+	return l.Line < other.Line || (l.Line == other.Line && l.Pos < other.Pos)
+}
+
 type TokenType string
 
 const (
-	TokenKeyword     TokenType = "keyword"
-	TokenString      TokenType = "string"
-	TokenNewline     TokenType = "newline"
-	TokenBraceOpen   TokenType = "{"
-	TokenBraceClose  TokenType = "}"
-	TokenEqual       TokenType = "="
-	TokenRelation    TokenType = "->"
-	TokenComment     TokenType = "comment"
-	TokenEof         TokenType = "EOF"
-	TokenName        TokenType = "name"
-	TokenDescription TokenType = "description"
-	TokenTags        TokenType = "tags"
-	TokenValue       TokenType = "value"
+	TokenKeyword    TokenType = "keyword"
+	TokenString     TokenType = "string"
+	TokenNewline    TokenType = "newline"
+	TokenBraceOpen  TokenType = "{"
+	TokenBraceClose TokenType = "}"
+	TokenEqual      TokenType = "="
+	TokenRelation   TokenType = "->"
+	TokenComment    TokenType = "comment"
+	TokenEof        TokenType = "EOF"
 )
 
 var logger *log.Logger
@@ -51,7 +55,31 @@ func initLogger() {
 }
 
 func Lexer(source string, content string, includer Includer) ([]Token, error) {
+	tokens, _, diagnostics := LexWithIncludes(source, content, includer)
+	if len(diagnostics) > 0 {
+		return tokens, errors.New(diagnostics[0].Message)
+	}
+	return tokens, nil
+}
+
+// Lexes the source together with the files it includes. Failing and cyclic includes are reported as diagnostics on
+// the !include directive and the rest of the file is still lexed.
+func LexWithIncludes(source string, content string, includer Includer) ([]Token, *IncludeGraph, []*Diagnostic) {
 	initLogger()
+	tokens, eof := tokenize(source, content)
+	r := &includeResolver{graph: newIncludeGraph(source), includer: includer}
+	tokens = r.expand(tokens, source, []string{source})
+	return append(tokens, eof), r.graph, r.diagnostics
+}
+
+// Splits the content into tokens without resolving its includes, comments are tokens too.
+func Tokenize(source string, content string) []Token {
+	tokens, _ := tokenize(source, content)
+	return tokens
+}
+
+// Splits the content into tokens, the returned EOF token is not part of the slice.
+func tokenize(source string, content string) ([]Token, Token) {
 	scanner := bufio.NewScanner(strings.NewReader(content))
 	scanner.Split(bufio.ScanRunes)
 	tokens := make([]Token, 0)
@@ -78,6 +106,7 @@ func Lexer(source string, content string, includer Includer) ([]Token, error) {
 			if !unicode.IsSpace([]rune(text)[0]) {
 				token.Content += text
 			} else {
+				token.End = Location{Source: source, Line: line, Pos: pos}
 				categorize(token)
 				tokens = append(tokens, *token)
 				token = nil
@@ -91,6 +120,10 @@ func Lexer(source string, content string, includer Includer) ([]Token, error) {
 				escaped = true
 			} else if text == `"` || text == "\n" {
 				token.Terminated = true
+				token.End = Location{Source: source, Line: line, Pos: pos + 1}
+				if text == "\n" {
+					token.End.Pos = pos
+				}
 				tokens = append(tokens, *token)
 				token = nil
 				state = "start"
@@ -100,6 +133,7 @@ func Lexer(source string, content string, includer Includer) ([]Token, error) {
 			}
 		case "singlelinecomment":
 			if text == "\n" {
+				token.End = Location{Source: source, Line: line, Pos: pos}
 				tokens = append(tokens, *token)
 				token = nil
 				state = "start"
@@ -112,15 +146,19 @@ func Lexer(source string, content string, includer Includer) ([]Token, error) {
 		case "multilinecomment":
 			token.Content += text
 			if strings.HasSuffix(token.Content, "*/") {
+				token.End = Location{Source: source, Line: line, Pos: pos + 1}
 				tokens = append(tokens, *token)
 				token = nil
 				state = "start"
 			}
 		}
-		if text == "\n" && state != "multilinecomment" {
-			token = &Token{Type: TokenNewline, Content: "", Location: Location{Source: source, Line: line, Pos: pos}}
-			tokens = append(tokens, *token)
-			token = nil
+		if text == "\n" {
+			// multiline comments span lines without ending the statement
+			if state != "multilinecomment" {
+				location := Location{Source: source, Line: line, Pos: pos}
+				tokens = append(tokens, Token{Type: TokenNewline, Content: "", Location: location, End: location})
+				token = nil
+			}
 			pos = 0
 			line++
 		} else {
@@ -128,39 +166,12 @@ func Lexer(source string, content string, includer Includer) ([]Token, error) {
 		}
 	}
 	if token != nil {
+		token.End = Location{Source: source, Line: line, Pos: pos}
 		categorize(token)
 		tokens = append(tokens, *token)
 	}
-	tokens, err := checkIncludedFiles(tokens, source, includer)
-	tokens = append(tokens, Token{Type: TokenEof, Content: "EOF", Location: Location{Source: source, Line: line, Pos: pos}})
-	return tokens, err
-}
-
-func checkIncludedFiles(tokens []Token, source string, in Includer) ([]Token, error) {
-	result := make([]Token, 0)
-	for i := 0; i < len(tokens); i++ {
-		if tokens[i].Content == "!include" && i+1 < len(tokens) {
-			path := tokens[i+1].Content // make it URI
-			fullpath := filepath.Join(filepath.Dir(source), path)
-			content, err := in.include(fullpath)
-			if err != nil {
-				logger.Printf("Error during include %s on absolute path %s cause: %s", path, fullpath, err)
-				return nil, err
-			}
-			included, err := Lexer(fullpath, content, in)
-			if err != nil {
-				return nil, err
-			}
-			result = append(result, tokens[i])
-			result = append(result, tokens[i+1])
-			result = append(result, Token{Type: TokenNewline, Content: "", Location: tokens[i].Location})
-			result = append(result, included[:len(included)-1]...)
-			i++
-		} else {
-			result = append(result, tokens[i])
-		}
-	}
-	return result, nil
+	eof := Location{Source: source, Line: line, Pos: pos}
+	return tokens, Token{Type: TokenEof, Content: "EOF", Location: eof, End: eof}
 }
 
 func categorize(token *Token) {
@@ -173,5 +184,10 @@ func categorize(token *Token) {
 		token.Type = TokenEqual
 	case "->":
 		token.Type = TokenRelation
+	default:
+		// relationships using an archetype, e.g. --https->
+		if token.Type == TokenKeyword && len(token.Content) > 4 && strings.HasPrefix(token.Content, "--") && strings.HasSuffix(token.Content, "->") {
+			token.Type = TokenRelation
+		}
 	}
 }
@@ -28,6 +28,12 @@ func TestLexer(t *testing.T) {
 		tokens, _ := Lexer(file, content, fake)
 		assert.Equal(t, TokenBraceClose, tokens[0].Type)
 	})
+	t.Run("should return relation for relationships with an archetype", func(t *testing.T) {
+		tokens, _ := Lexer(file, "a --https-> b \"--https->\"", fake)
+		assert.Equal(t, TokenRelation, tokens[1].Type)
+		assert.Equal(t, "--https->", tokens[1].Content)
+		assert.Equal(t, TokenString, tokens[3].Type)
+	})
 	t.Run("should handle multiple tokens found", func(t *testing.T) {
 		content := "workspace declaration"
 		tokens, _ := Lexer(file, content, fake)
@@ -155,4 +161,20 @@ func TestLexer(t *testing.T) {
 		tokens, _ := Lexer(file, content, fake)
 		assert.Equal(t, 4, len(tokens))
 	})
+	t.Run("tokens know where they end", func(t *testing.T) {
+		content := "person \"User\" {"
+		tokens, _ := Lexer(file, content, fake)
+		assert.Equal(t, Location{Source: file, Line: 0, Pos: 6}, tokens[0].End)
+		assert.Equal(t, Location{Source: file, Line: 0, Pos: 13}, tokens[1].End)
+		assert.Equal(t, Location{Source: file, Line: 0, Pos: 15}, tokens[2].End)
+	})
+	t.Run("multiline comments advance the line", func(t *testing.T) {
+		content := "/* first\nsecond */\nworkspace"
+		tokens, _ := Lexer(file, content, fake)
+		if assert.Equal(t, 4, len(tokens)) {
+			assert.Equal(t, TokenComment, tokens[0].Type)
+			assert.Equal(t, Location{Source: file, Line: 1, Pos: 9}, tokens[0].End)
+			assert.Equal(t, 2, tokens[2].Location.Line)
+		}
+	})
 }
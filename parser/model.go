@@ -0,0 +1,273 @@
+package parser
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Details are the attributes shared by elements and relationships, which may be amended after their definition.
+type Details struct {
+	Description  string
+	Technology   string
+	URL          string
+	Tags         []string
+	Properties   map[string]string
+	Perspectives []Perspective
+}
+
+// Perspective describes an element or a relationship from a point of view like security or operations.
+type Perspective struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Value       string `json:"value"`
+}
+
+// Element is a single element of the model, e.g. a person, a container or a deployment node.
+type Element struct {
+	Details
+	// Fully qualified identifier, empty when the element is not assigned to one
+	Identifier string
+	Type       string
+	Name       string
+	// Type of custom elements, e.g. Hardware
+	Metadata string
+	Parent   *Element
+	// Location of the identifier or the keyword defining the element
+	Definition Range
+	// Documentation and decisions of software systems and containers
+	Docs *Documentation
+	Adrs *ADR
+}
+
+// Relationship connects two elements of the model.
+type Relationship struct {
+	Details
+	Identifier  string
+	Source      *Element
+	Destination *Element
+	Definition  Range
+	// Relationship the implied one was created for, nil for relationships defined in the model
+	LinkedRelationship *Relationship
+}
+
+// Archetype is a named element or relationship type with default details, e.g.
+// application = container { technology "Java" }.
+type Archetype struct {
+	Details
+	Name string
+	// Element type the archetype is based on, -> for relationship archetypes
+	Type     string
+	Metadata string
+	// Archetype the archetype is based on, nil when it is based on an element type
+	Base       *Archetype
+	Definition Range
+}
+
+// Reference is an identifier in the source pointing to an element or a relationship, definitions are references too.
+type Reference struct {
+	Range        Range
+	Identifier   string
+	Element      *Element
+	Relationship *Relationship
+	Archetype    *Archetype
+	Definition   bool
+}
+
+// Tags every element of the given type gets implicitly.
+var defaultTags = map[string][]string{
+	"person":                 {"Element", "Person"},
+	"softwareSystem":         {"Element", "Software System"},
+	"container":              {"Element", "Container"},
+	"component":              {"Element", "Component"},
+	"deploymentNode":         {"Element", "Deployment Node"},
+	"infrastructureNode":     {"Element", "Infrastructure Node"},
+	"softwareSystemInstance": {"Software System Instance"},
+	"containerInstance":      {"Container Instance"},
+	"element":                {"Element"},
+}
+
+// Elements which group others without being elements of the model themselves.
+var transparentElements = map[string]bool{
+	"group":           true,
+	"enterprise":      true,
+	"deploymentGroup": true,
+}
+
+// Prefixes of the canonical names of element types.
+var canonicalPrefixes = map[string]string{
+	"person":                 "Person://",
+	"softwareSystem":         "SoftwareSystem://",
+	"container":              "Container://",
+	"component":              "Component://",
+	"deploymentNode":         "DeploymentNode://",
+	"infrastructureNode":     "InfrastructureNode://",
+	"softwareSystemInstance": "SoftwareSystemInstance://",
+	"containerInstance":      "ContainerInstance://",
+	"element":                "CustomElement://",
+}
+
+// Adds the tags which the details do not have yet.
+func (d *Details) addTags(tags ...string) {
+	for _, t := range tags {
+		if !slices.Contains(d.Tags, t) {
+			d.Tags = append(d.Tags, t)
+		}
+	}
+}
+
+// Adds the perspective or replaces the one with the same name.
+func (d *Details) addPerspective(p Perspective) {
+	i := slices.IndexFunc(d.Perspectives, func(existing Perspective) bool { return existing.Name == p.Name })
+	if i < 0 {
+		d.Perspectives = append(d.Perspectives, p)
+		return
+	}
+	d.Perspectives[i] = p
+}
+
+// Returns a copy of the details which can be amended independently.
+func (d Details) clone() Details {
+	d.Tags = slices.Clone(d.Tags)
+	d.Perspectives = slices.Clone(d.Perspectives)
+	d.Properties = maps.Clone(d.Properties)
+	if d.Properties == nil {
+		d.Properties = make(map[string]string)
+	}
+	return d
+}
+
+// Deployment environments are kept among the elements to scope their deployment nodes, but they are not elements
+// of the model in Structurizr.
+func (e *Element) isEnvironment() bool {
+	return e.Type == "deploymentEnvironment"
+}
+
+// Returns the canonical name of the element, e.g. Container://System.API or DeploymentNode://Live/Server.
+func (e *Element) CanonicalName() string {
+	prefix, ok := canonicalPrefixes[e.Type]
+	if !ok {
+		return ""
+	}
+	separator := "."
+	if strings.HasPrefix(prefix, "Deployment") || strings.HasPrefix(prefix, "Infrastructure") || strings.HasSuffix(e.Type, "Instance") {
+		separator = "/"
+	}
+	names := []string{e.Name}
+	for p := e.Parent; p != nil; p = p.Parent {
+		names = append([]string{p.Name}, names...)
+	}
+	return prefix + strings.Join(names, separator)
+}
+
+func newModel() *Model {
+	return &Model{
+		People:                 make(map[string]*Person),
+		Groups:                 make(map[string]*Group),
+		References:             make(map[string]interface{}),
+		SoftwareSystems:        make(map[string]*SoftwareSystem),
+		DeploymentEnvironments: make(map[string]*DeploymentEnvironment),
+		Elements:               make([]*Element, 0),
+		Relationships:          make([]*Relationship, 0),
+		Usages:                 make([]*Reference, 0),
+		elements:               make(map[string]*Element),
+		relationships:          make(map[string]*Relationship),
+		Archetypes:             make(map[string]*Archetype),
+	}
+}
+
+// Copies the elements and relationships of the base workspace, references stay in the base files.
+func (m *Model) inherit(base *Model) {
+	m.Elements = append(m.Elements, base.Elements...)
+	m.Relationships = append(m.Relationships, base.Relationships...)
+	for id, e := range base.elements {
+		m.elements[id] = e
+	}
+	for id, r := range base.relationships {
+		m.relationships[id] = r
+	}
+	for name, a := range base.Archetypes {
+		m.Archetypes[name] = a
+	}
+}
+
+// Returns the element types of the element archetypes keyed by their lowercase name.
+func (m *Model) elementArchetypes() map[string]string {
+	types := make(map[string]string)
+	for name, a := range m.Archetypes {
+		if a.Type != "->" {
+			types[name] = a.Type
+		}
+	}
+	return types
+}
+
+// Returns the element with the given identifier, identifiers are case-insensitive.
+func (m *Model) Element(identifier string) *Element {
+	return m.elements[strings.ToLower(identifier)]
+}
+
+// Returns the element with the given canonical name.
+func (m *Model) ElementByCanonicalName(name string) *Element {
+	for _, e := range m.Elements {
+		if e.CanonicalName() == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// Returns the archetype with the given name, archetypes are keyed by their lowercase name.
+func (m *Model) Archetype(name string) *Archetype {
+	return m.Archetypes[strings.ToLower(name)]
+}
+
+// Returns the relationship with the given identifier.
+func (m *Model) Relationship(identifier string) *Relationship {
+	return m.relationships[strings.ToLower(identifier)]
+}
+
+// Returns the reference under the location, the end of the identifier is part of it as editors place the cursor
+// after the last character.
+func (m *Model) ReferenceAt(loc Location) *Reference {
+	for _, r := range m.Usages {
+		if loc.Source == r.Range.Start.Source && !loc.Before(r.Range.Start) && !r.Range.End.Before(loc) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (m *Model) addElement(e *Element) {
+	m.Elements = append(m.Elements, e)
+	if e.Identifier != "" {
+		m.elements[strings.ToLower(e.Identifier)] = e
+	}
+}
+
+// Registers another identifier of an existing element.
+func (m *Model) alias(identifier string, e *Element) {
+	m.elements[strings.ToLower(identifier)] = e
+}
+
+func (m *Model) addRelationship(r *Relationship) {
+	m.Relationships = append(m.Relationships, r)
+	if r.Identifier != "" {
+		m.relationships[strings.ToLower(r.Identifier)] = r
+	}
+}
+
+// Returns the tags of a comma separated list.
+func splitTags(tags string) []string {
+	result := make([]string, 0)
+	for _, t := range strings.Split(tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+func tokenRange(t *Token) Range {
+	return Range{Start: t.Location, End: t.End}
+}
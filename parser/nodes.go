@@ -0,0 +1,278 @@
+package parser
+
+// Node is the typed view of a node of the syntax tree, its concrete type tells what the node represents and its
+// methods return the tokens by their meaning instead of their position.
+type Node interface {
+	AST() *ASTNode
+}
+
+type WorkspaceNode struct{ *ASTNode }
+
+type ModelNode struct{ *ASTNode }
+
+type ViewsNode struct{ *ASTNode }
+
+// ElementNode is an element of the model or of a deployment environment, its type is either an element type or the
+// name of an archetype.
+type ElementNode struct{ *ASTNode }
+
+type RelationshipNode struct{ *ASTNode }
+
+type ViewNode struct{ *ASTNode }
+
+// DirectiveNode is a statement starting with !, like !include or !docs.
+type DirectiveNode struct{ *ASTNode }
+
+// PropertyNode is a key-value pair of a properties, perspectives or users block.
+type PropertyNode struct{ *ASTNode }
+
+// StatementNode is any other statement, like the description of an element or the include of a view.
+type StatementNode struct{ *ASTNode }
+
+// ThemeNode is a theme or themes statement of the styles.
+type ThemeNode struct{ *ASTNode }
+
+// URLNode is the url of an element, a relationship or a view.
+type URLNode struct{ *ASTNode }
+
+// Returns the node itself, so every typed node gives access to the syntax tree it wraps.
+func (n *ASTNode) AST() *ASTNode {
+	return n
+}
+
+// Returns the typed view of the node, nil for the root, blocks without a meaning of their own, assignments,
+// identifiers and braces. The parser types the nodes while it builds the tree.
+func (n *ASTNode) Typed() Node {
+	return n.typed
+}
+
+func newTyped(n *ASTNode) Node {
+	switch n.Kind {
+	case NodeWorkspace:
+		return &WorkspaceNode{n}
+	case NodeModel:
+		return &ModelNode{n}
+	case NodeViews:
+		return &ViewsNode{n}
+	case NodeElement:
+		return &ElementNode{n}
+	case NodeRelationship:
+		return &RelationshipNode{n}
+	case NodeView:
+		return &ViewNode{n}
+	case NodeDirective:
+		return &DirectiveNode{n}
+	case NodeProperty:
+		return &PropertyNode{n}
+	case NodeStatement:
+		switch n.Content {
+		case "theme", "themes":
+			return &ThemeNode{n}
+		case "url":
+			return &URLNode{n}
+		}
+		return &StatementNode{n}
+	}
+	return nil
+}
+
+func (w *WorkspaceNode) Name() *Token {
+	return w.Attribute(RoleName)
+}
+
+func (w *WorkspaceNode) Description() *Token {
+	return w.Attribute(RoleDescription)
+}
+
+// Returns the path of the extended workspace, nil when it extends none.
+func (w *WorkspaceNode) Base() *Token {
+	return w.Attribute(RoleBase)
+}
+
+// Returns the first model block of the workspace, nil when it has none.
+func (w *WorkspaceNode) Model() *ModelNode {
+	for _, c := range w.Children {
+		if c.Kind == NodeModel {
+			return &ModelNode{c}
+		}
+	}
+	return nil
+}
+
+// Returns the first views block of the workspace, nil when it has none.
+func (w *WorkspaceNode) Views() *ViewsNode {
+	for _, c := range w.Children {
+		if c.Kind == NodeViews {
+			return &ViewsNode{c}
+		}
+	}
+	return nil
+}
+
+// Returns the keyword of the element, e.g. container or the name of an archetype.
+func (e *ElementNode) ElementType() string {
+	return e.Content
+}
+
+// Returns the identifier the element is assigned to, nil when it is not assigned.
+func (e *ElementNode) Identifier() *ASTNode {
+	return assignedIdentifier(e.ASTNode)
+}
+
+func (e *ElementNode) Name() *Token {
+	return e.Attribute(RoleName)
+}
+
+func (e *ElementNode) Description() *Token {
+	return e.Attribute(RoleDescription)
+}
+
+func (e *ElementNode) Technology() *Token {
+	return e.Attribute(RoleTechnology)
+}
+
+func (e *ElementNode) Tags() *Token {
+	return e.Attribute(RoleTags)
+}
+
+func (e *ElementNode) Metadata() *Token {
+	return e.Attribute(RoleMetadata)
+}
+
+// Returns the software system or container deployed by an instance, nil for other elements.
+func (e *ElementNode) Instance() *Token {
+	return e.Attribute(RoleIdentifier)
+}
+
+// Returns the identifier the relationship is assigned to, nil when it is not assigned.
+func (r *RelationshipNode) Identifier() *ASTNode {
+	return assignedIdentifier(r.ASTNode)
+}
+
+// Returns the source of the relationship, nil when it is the enclosing element.
+func (r *RelationshipNode) Source() *Token {
+	return r.Attribute(RoleSource)
+}
+
+func (r *RelationshipNode) Destination() *Token {
+	return r.Attribute(RoleDestination)
+}
+
+func (r *RelationshipNode) Description() *Token {
+	return r.Attribute(RoleDescription)
+}
+
+func (r *RelationshipNode) Technology() *Token {
+	return r.Attribute(RoleTechnology)
+}
+
+func (r *RelationshipNode) Tags() *Token {
+	return r.Attribute(RoleTags)
+}
+
+// Returns the archetype of the operator like --https->, empty for plain relationships.
+func (r *RelationshipNode) Archetype() string {
+	return relationshipArchetype(r.Content)
+}
+
+// Returns the keyword of the view, e.g. systemContext.
+func (v *ViewNode) ViewType() string {
+	return v.Content
+}
+
+// Returns the element the view is about, the key of the base view for filtered views.
+func (v *ViewNode) Scope() *Token {
+	return v.Attribute(RoleScope)
+}
+
+func (v *ViewNode) Key() *Token {
+	return v.Attribute(RoleKey)
+}
+
+func (v *ViewNode) Description() *Token {
+	return v.Attribute(RoleDescription)
+}
+
+// Returns the deployment environment of deployment views, nil for other views.
+func (v *ViewNode) Environment() *Token {
+	return v.Attribute(RoleEnvironment)
+}
+
+// Returns the directive including its !, e.g. !include.
+func (d *DirectiveNode) Directive() string {
+	return d.Content
+}
+
+// Returns the path of !include, !docs and !adrs.
+func (d *DirectiveNode) Path() *Token {
+	return d.Attribute(RolePath)
+}
+
+func (d *DirectiveNode) Importer() *Token {
+	return d.Attribute(RoleImporter)
+}
+
+// Returns the element or relationship reopened by !element, !extend, !ref or !relationship.
+func (d *DirectiveNode) Target() *Token {
+	return d.Attribute(RoleTarget)
+}
+
+// Returns the expression of !elements and !relationships.
+func (d *DirectiveNode) Expression() *Token {
+	return d.Attribute(RoleExpression)
+}
+
+// Returns the script of !script and the plugin of !plugin, which are files unless the script is inline or the
+// plugin is a class name.
+func (d *DirectiveNode) File() *Token {
+	return d.Attribute(RoleFile)
+}
+
+// Reports whether the directive includes files or URLs.
+func (d *DirectiveNode) Include() bool {
+	return d.Content == "!include"
+}
+
+// Reports whether the directive reopens an element, so its block contains elements and relationships.
+func (d *DirectiveNode) ElementDirective() bool {
+	return elementDirectives[d.Content]
+}
+
+// Returns the option of !identifiers and !impliedRelationships.
+func (d *DirectiveNode) Option() *Token {
+	return d.Attribute(RoleOption)
+}
+
+func (p *PropertyNode) Name() *Token {
+	return &p.Token
+}
+
+// Returns the value of the property, nil when it is missing.
+func (p *PropertyNode) Value() *Token {
+	if len(p.Attributes) == 0 {
+		return nil
+	}
+	return p.Attributes[0]
+}
+
+// Returns the URLs and paths of the themes.
+func (t *ThemeNode) Themes() []*Token {
+	return t.Attributes
+}
+
+// Returns the url, nil when it is missing.
+func (u *URLNode) URL() *Token {
+	if len(u.Attributes) == 0 {
+		return nil
+	}
+	return u.Attributes[0]
+}
+
+// Returns the identifier node of the assignment holding the node, nil when the node is not assigned.
+func assignedIdentifier(node *ASTNode) *ASTNode {
+	parent := node.Parent
+	if parent == nil || parent.Kind != NodeAssignment || len(parent.Children) < 2 || parent.Children[len(parent.Children)-1] != node {
+		return nil
+	}
+	return parent.Children[0]
+}
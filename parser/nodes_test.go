@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodes(t *testing.T) {
+	file := "test.dsl"
+	fake := &FakeIncluder{}
+	t.Run("workspace exposes its attributes and blocks", func(t *testing.T) {
+		sut := New(file, "workspace \"Name\" \"Description\" {\nmodel {\n}\nviews {\n}\n}", fake)
+		ast, _ := sut.Parse()
+		workspace, ok := ast.Children[0].Typed().(*WorkspaceNode)
+		if assert.True(t, ok) {
+			assert.Equal(t, "Name", workspace.Name().Content)
+			assert.Equal(t, "Description", workspace.Description().Content)
+			assert.Nil(t, workspace.Base())
+			assert.NotNil(t, workspace.Model())
+			assert.NotNil(t, workspace.Views())
+		}
+		assert.Nil(t, ast.Typed())
+	})
+	t.Run("workspace exposes the extended workspace", func(t *testing.T) {
+		sut := New(file, "workspace extends base.dsl {\n}", fake)
+		ast, _ := sut.Parse()
+		workspace, ok := ast.Children[0].Typed().(*WorkspaceNode)
+		if assert.True(t, ok) {
+			assert.Equal(t, "base.dsl", workspace.Base().Content)
+			assert.Nil(t, workspace.Model())
+		}
+	})
+	t.Run("elements know their identifier", func(t *testing.T) {
+		sut := New(file, "workspace {\nmodel {\napi = container \"Api\" \"Serves\" \"Go\" \"Tag\"\nperson \"User\"\n}\n}", fake)
+		ast, _ := sut.Parse()
+		model := ast.Children[0].Children[1]
+		api, ok := model.Children[1].Children[1].Typed().(*ElementNode)
+		if assert.True(t, ok) {
+			assert.Equal(t, "container", api.ElementType())
+			assert.Equal(t, "api", api.Identifier().Content)
+			assert.Equal(t, "Api", api.Name().Content)
+			assert.Equal(t, "Serves", api.Description().Content)
+			assert.Equal(t, "Go", api.Technology().Content)
+			assert.Equal(t, "Tag", api.Tags().Content)
+		}
+		user, ok := model.Children[2].Typed().(*ElementNode)
+		if assert.True(t, ok) {
+			assert.Nil(t, user.Identifier())
+			assert.Equal(t, "User", user.Name().Content)
+		}
+	})
+	t.Run("relationships expose their ends", func(t *testing.T) {
+		sut := New(file, "workspace {\nmodel {\nrel = u -> s \"Uses\" \"HTTP\"\n}\n}", fake)
+		ast, _ := sut.Parse()
+		rel, ok := ast.Children[0].Children[1].Children[1].Children[1].Typed().(*RelationshipNode)
+		if assert.True(t, ok) {
+			assert.Equal(t, "rel", rel.Identifier().Content)
+			assert.Equal(t, "u", rel.Source().Content)
+			assert.Equal(t, "s", rel.Destination().Content)
+			assert.Equal(t, "Uses", rel.Description().Content)
+			assert.Equal(t, "HTTP", rel.Technology().Content)
+			assert.Equal(t, "", rel.Archetype())
+		}
+	})
+	t.Run("views expose their scope and key", func(t *testing.T) {
+		sut := New(file, "workspace {\nviews {\ncontainer system \"key\" {\ninclude *\n}\n}\n}", fake)
+		ast, _ := sut.Parse()
+		view, ok := ast.Children[0].Children[1].Children[1].Typed().(*ViewNode)
+		if assert.True(t, ok) {
+			assert.Equal(t, "container", view.ViewType())
+			assert.Equal(t, "system", view.Scope().Content)
+			assert.Equal(t, "key", view.Key().Content)
+			_, ok = view.Children[1].Typed().(*StatementNode)
+			assert.True(t, ok)
+		}
+	})
+	t.Run("nodes are typed by the parser", func(t *testing.T) {
+		sut := New(file, "workspace {\n}", fake)
+		ast, _ := sut.Parse()
+		workspace := ast.Children[0]
+		assert.Same(t, workspace.Typed(), workspace.Typed())
+		assert.Same(t, workspace, workspace.Typed().AST())
+	})
+	t.Run("themes and urls have their own types", func(t *testing.T) {
+		sut := New(file, "workspace {\nmodel {\nss = softwareSystem \"System\" {\nurl https://example.com\n}\n}\nviews {\ntheme default\nthemes a.json b.json\n}\n}", fake)
+		ast, _ := sut.Parse()
+		workspace := ast.Children[0]
+		url, ok := workspace.Children[1].Children[1].Children[1].Children[1].Typed().(*URLNode)
+		if assert.True(t, ok) {
+			assert.Equal(t, "https://example.com", url.URL().Content)
+		}
+		views := workspace.Children[2]
+		theme, ok := views.Children[1].Typed().(*ThemeNode)
+		if assert.True(t, ok) {
+			assert.Equal(t, "default", theme.Themes()[0].Content)
+		}
+		themes, ok := views.Children[2].Typed().(*ThemeNode)
+		if assert.True(t, ok) {
+			assert.Equal(t, 2, len(themes.Themes()))
+		}
+	})
+	t.Run("directives tell what they do", func(t *testing.T) {
+		sut := New(file, "workspace {\n!script build.groovy\nmodel {\n!include people.dsl\n!extend ss {\n}\n}\n}", fake)
+		ast, _ := sut.Parse()
+		workspace := ast.Children[0]
+		script := workspace.Children[1].Typed().(*DirectiveNode)
+		assert.Equal(t, "build.groovy", script.File().Content)
+		assert.False(t, script.Include())
+		model := workspace.Children[2]
+		include := model.Children[1].Typed().(*DirectiveNode)
+		assert.True(t, include.Include())
+		assert.False(t, include.ElementDirective())
+		extend := model.Children[len(model.Children)-2].Typed().(*DirectiveNode)
+		assert.Equal(t, "!extend", extend.Directive())
+		assert.True(t, extend.ElementDirective())
+	})
+	t.Run("directives expose their path", func(t *testing.T) {
+		sut := New(file, "workspace {\n!docs docs\nmodel {\n}\n}", fake)
+		ast, _ := sut.Parse()
+		docs, ok := ast.Children[0].Children[1].Typed().(*DirectiveNode)
+		if assert.True(t, ok) {
+			assert.Equal(t, "!docs", docs.Directive())
+			assert.Equal(t, "docs", docs.Path().Content)
+		}
+	})
+}
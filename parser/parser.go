@@ -1,24 +1,32 @@
 package parser
 
 import (
-	"fmt"
 	"strings"
 )
 
 type Parser struct {
 	root        *ASTNode
 	tokens      []Token
+	graph       *IncludeGraph
 	position    int
 	diagnostics []*Diagnostic
+	// element types of the archetypes defined so far
+	archetypes map[string]string
 }
 
 func New(source string, content string, in Includer) *Parser {
-	tokens, _ := Lexer(source, content, in)
-	return &Parser{tokens: tokens, root: NewNode(&Token{Content: "root"}, "root"), position: 0, diagnostics: make([]*Diagnostic, 0)}
+	tokens, graph, diagnostics := LexWithIncludes(source, content, in)
+	return &Parser{tokens: tokens, graph: graph, root: NewNode(&Token{Content: "root", Location: Location{Source: source}}, NodeRoot), position: 0, diagnostics: diagnostics, archetypes: make(map[string]string)}
+}
+
+// Returns the files included by the parsed source.
+func (p *Parser) IncludeGraph() *IncludeGraph {
+	return p.graph
 }
 
 type Workspace struct {
 	Name          string
+	Extends       string
 	Properties    map[string]string
 	Identifiers   string
 	Docs          *Documentation
@@ -42,87 +50,25 @@ type Documentation struct {
 }
 
 type ADR struct {
-	Path string
-	Fqcn string
-}
-
-type ASTNode struct {
-	Token
-	Parent     *ASTNode
-	Type       string
-	Value      string
-	Attributes []*Token
-	Children   []*ASTNode
-}
-
-func NewNode(token *Token, t string) *ASTNode {
-	return &ASTNode{Token: *token, Type: t, Attributes: make([]*Token, 0), Children: make([]*ASTNode, 0)}
-}
-func displayTree(node *ASTNode, prefix string, isLast bool) string {
-	var connector string
-	var sb strings.Builder
-	if isLast {
-		connector = "└──"
-	} else {
-		connector = "├──"
-	}
-	sb.WriteString(fmt.Sprintf("%s%s %s (Attributes: %v)\n", prefix, connector, node.Type+" "+node.Content, node.Attributes))
-	newPrefix := prefix
-	if isLast {
-		newPrefix += "    "
-	} else {
-		newPrefix += "│   "
-	}
-
-	for i, child := range node.Children {
-		sb.WriteString(displayTree(child, newPrefix, i == len(node.Children)-1))
-	}
-	return sb.String()
-}
-func (n *ASTNode) ToString() string {
-	attributes := mapToString(n.Attributes)
-	children := ""
-	if n.Children != nil {
-		for _, c := range n.Children {
-			children += c.ToString()
-		}
-	}
-	return fmt.Sprintf("(%s %s %s)", n.Content, attributes, children)
-}
-
-func (n *ASTNode) AddChild(c *ASTNode) {
-	c.Parent = n
-	n.Children = append(n.Children, c)
-}
-
-func (n *ASTNode) HasChild(t TokenType) bool {
-	for _, c := range n.Children {
-		if c.Token.Type == t {
-			return true
-		}
-	}
-	return false
-
-}
-func mapToString(m []*Token) string {
-	var builder strings.Builder
-
-	for _, value := range m {
-		builder.WriteString(fmt.Sprintf("(%s) ", value.Content))
-	}
-
-	// Trim the trailing space
-	result := strings.TrimSpace(builder.String())
-	return result
+	Path      string
+	Fqcn      string
+	Decisions []*Decision
 }
 
 type Model struct {
 	Identifiers            string
+	ImpliedRelationships   string
 	People                 map[string]*Person
 	Groups                 map[string]*Group
 	SoftwareSystems        map[string]*SoftwareSystem
 	DeploymentEnvironments map[string]*DeploymentEnvironment
 	References             map[string]interface{}
+	Elements               []*Element
+	Relationships          []*Relationship
+	Usages                 []*Reference
+	Archetypes             map[string]*Archetype
+	elements               map[string]*Element
+	relationships          map[string]*Relationship
 }
 
 type Group struct {
@@ -139,7 +85,9 @@ type SoftwareSystem struct {
 type Person struct {
 	Name string
 }
-type ViewSet struct{}
+type ViewSet struct {
+	Views []*View
+}
 
 type DiagnosticSeverity string
 
@@ -148,93 +96,219 @@ var (
 	DiagnosticWarning DiagnosticSeverity = "warning"
 )
 
+// DiagnosticCode identifies problems tools like quick fixes act on, so they do not depend on the message.
+type DiagnosticCode string
+
+const (
+	CodeMissingModel      DiagnosticCode = "missing-model"
+	CodeMissingViews      DiagnosticCode = "missing-views"
+	CodeOpeningBrace      DiagnosticCode = "opening-brace"
+	CodeClosingBrace      DiagnosticCode = "closing-brace"
+	CodeInvalidOption     DiagnosticCode = "invalid-option"
+	CodeUnknownIdentifier DiagnosticCode = "unknown-identifier"
+)
+
 type Diagnostic struct {
 	Message  string
 	Location Location
+	// End of the problematic range, zero when only the start is known
+	End      Location
 	Severity DiagnosticSeverity
+	// Empty for problems without a code
+	Code DiagnosticCode
+	// Details of the problem depending on its code, the possible values of an invalid option or the unknown
+	// identifier
+	Data []string
+}
+
+// Returns the views of the workspace, nil when it has none.
+func (w *Workspace) Views() *ViewSet {
+	return w.views
 }
 
 func (p *Parser) Parse() (*ASTNode, []*Diagnostic) {
-	p.parse(p.root)
+	p.parseBlock(p.root, nil)
+	computeRanges(p.root)
 	logger.Print(displayTree(p.root, "", false))
 	return p.root, p.diagnostics
 }
 
-func (p *Parser) parse(parent *ASTNode) {
-	if parent == nil {
-		return
-	}
-	for {
-		if !p.hasTokens() {
-			// if the first children is open then the last should be close
-			if len(parent.Children) > 0 && parent.Children[0].Token.Type == TokenBraceOpen && parent.Children[len(parent.Children)-1].Token.Type != TokenBraceClose {
-				p.addDiagnostic(DiagnosticError, "Unexpected EOF, expected }", parent.Children[len(parent.Children)-1].Location)
+// Parses the statements of a block line by line until its closing brace or EOF. Errors are reported as diagnostics
+// and the parser resynchronises on the next line or the matching brace, so the tree always covers the whole file.
+func (p *Parser) parseBlock(parent *ASTNode, open *Token) {
+	var last *ASTNode
+	for p.hasTokens() {
+		tokens := withoutComments(p.readLine())
+		if len(tokens) == 0 {
+			continue
+		}
+		switch tokens[0].Type {
+		case TokenBraceClose:
+			if p.closeBlock(parent, open, tokens[0]) {
+				return
+			}
+			continue
+		case TokenBraceOpen:
+			p.addDiagnostic(DiagnosticError, "Opening curly brace symbols ({) must be on the same line.", tokens[0].Location).Code = CodeOpeningBrace
+			// assume the brace belongs to the statement on the previous line
+			if owner := blockOwner(last); owner != nil && !owner.HasChild(TokenBraceOpen) {
+				owner.AddChild(NewNode(tokens[0], NodeBlockStart))
+				p.parseBlock(owner, tokens[0])
+			} else {
+				p.skipBlock()
 			}
-			return
+			continue
 		}
-		tokens := p.readLine()
-		logger.Println(tokens)
-		var current *ASTNode
-		for i, t := range tokens {
-			switch t.Type {
-			case TokenKeyword:
-				// lookahead for equal if we are the first in the line
-				if i+1 < len(tokens) && i == 0 && tokens[1].Type == TokenEqual {
-					assign := NewNode(tokens[1], "assignment")
-					parent.AddChild(assign)
-					parent = assign
-				}
-				if current == nil {
-					current = NewNode(t, string(t.Type))
-					parent.AddChild(current)
-				} else if parent.Token.Type == TokenEqual {
-					current = NewNode(t, string(t.Type))
-					parent.AddChild(current)
-				} else {
-					// handle subsequent keywords as attributes
-					current.Attributes = append(current.Attributes, t)
-				}
-			case TokenEqual:
+		current, rest := p.parseStatement(parent, tokens)
+		last = current
+		if len(rest) == 0 {
+			continue
+		}
+		if rest[0].Type == TokenBraceClose {
+			p.addDiagnostic(DiagnosticError, "Closing curly brace symbols (}) must be on a line of their own.", rest[0].Location).Code = CodeClosingBrace
+			if p.closeBlock(parent, open, rest[0]) {
+				return
+			}
+			continue
+		}
+		owner := blockOwner(current)
+		if owner == nil {
+			p.addDiagnostic(DiagnosticError, "Unexpected {", rest[0].Location)
+			p.skipBlock()
+			continue
+		}
+		owner.AddChild(NewNode(rest[0], NodeBlockStart))
+		if len(rest) > 1 && rest[1].Type == TokenBraceClose {
+			// empty block on a single line
+			p.addDiagnostic(DiagnosticError, "Closing curly brace symbols (}) must be on a line of their own.", rest[1].Location).Code = CodeClosingBrace
+			owner.AddChild(NewNode(rest[1], NodeBlockEnd))
+			continue
+		}
+		p.parseBlock(owner, rest[0])
+	}
+	if open != nil {
+		location := open.Location
+		if len(parent.Children) > 0 {
+			location = parent.Children[len(parent.Children)-1].Location
+		}
+		p.addDiagnostic(DiagnosticError, "Unexpected EOF, expected }", location)
+	}
+}
+
+// Builds the node of a single line and returns the remaining tokens starting from the first brace.
+func (p *Parser) parseStatement(parent *ASTNode, tokens []*Token) (*ASTNode, []*Token) {
+	var statement *ASTNode
+	target := parent
+	if len(tokens) > 1 && tokens[0].Type == TokenKeyword && tokens[1].Type == TokenEqual {
+		// assignments hold the identifier and the assigned element as children
+		statement = NewNode(tokens[1], NodeAssignment)
+		identifier := NewNode(tokens[0], NodeIdentifier)
+		identifier.Token.Role = RoleIdentifier
+		statement.AddChild(identifier)
+		parent.AddChild(statement)
+		target = statement
+		tokens = tokens[2:]
+	}
+	end := len(tokens)
+	for i, t := range tokens {
+		if t.Type == TokenBraceOpen || t.Type == TokenBraceClose {
+			end = i
+			break
+		}
+	}
+	line, rest := tokens[:end], tokens[end:]
+	var current *ASTNode
+	if operator := relationshipOperator(line); operator != nil {
+		current = newRelationship(operator, line)
+		target.AddChild(current)
+	} else {
+		for _, t := range line {
+			if t.Type != TokenKeyword && t.Type != TokenString {
 				continue
-			case TokenString:
-				if current == nil {
-					current = NewNode(t, string(t.Type))
-					parent.AddChild(current)
-				} else {
-					current.Attributes = append(current.Attributes, t)
-				}
-			case TokenBraceOpen:
-				if i == 0 {
-					p.addDiagnostic(DiagnosticError, "Opening curly brace symbols ({) must be on the same line.", t.Location)
-					return
-				}
-				brace := NewNode(t, string(t.Type))
-				current.AddChild(brace)
-				p.parse(current)
-				// one level down
-			case TokenBraceClose:
-				if i != 0 {
-					p.addDiagnostic(DiagnosticError, "Closing curly brace symbols (}) must be on a line of their own.", t.Location)
-				}
-				brace := NewNode(t, string(t.Type))
-				p.addClosingBraces(parent, brace)
+			}
+			if current == nil {
+				current = NewNode(t, NodeStatement)
+				target.AddChild(current)
+			} else {
+				// handle subsequent keywords as attributes
+				current.Attributes = append(current.Attributes, t)
+			}
+		}
+		if current != nil {
+			classify(current, parent, p.archetypes)
+		}
+	}
+	if statement == nil {
+		return current, rest
+	}
+	if parent.Kind == NodeArchetypes && current != nil && current.Kind == NodeElement {
+		// later statements may use the archetype like an element type
+		p.archetypes[strings.ToLower(statement.Children[0].Content)] = elementType(current.Content, p.archetypes)
+	}
+	return statement, rest
+}
+
+// Returns the relationship operator if the line is a relationship with an explicit or implicit source.
+func relationshipOperator(tokens []*Token) *Token {
+	for i, t := range tokens {
+		if i > 1 {
+			break
+		}
+		if t.Type == TokenRelation {
+			return t
+		}
+	}
+	return nil
+}
+
+// Adds the closing brace to the block, returns false if there is no open block to close.
+func (p *Parser) closeBlock(parent *ASTNode, open *Token, brace *Token) bool {
+	if open == nil {
+		p.addDiagnostic(DiagnosticError, "Expected EOF, got }", brace.Location)
+		return false
+	}
+	parent.AddChild(NewNode(brace, NodeBlockEnd))
+	return true
+}
+
+// Skips tokens until the brace matching an already consumed opening brace.
+func (p *Parser) skipBlock() {
+	depth := 1
+	for p.hasTokens() {
+		t := p.nextToken()
+		if t.Type == TokenBraceOpen {
+			depth++
+		} else if t.Type == TokenBraceClose {
+			depth--
+			if depth == 0 {
 				return
 			}
 		}
 	}
 }
 
-func (p *Parser) addClosingBraces(node *ASTNode, brace *ASTNode) {
-	// recursively walk up the tree and add to a parent where braces are missing
+// Returns the node a block belongs to, for assignments it is the assigned element.
+func blockOwner(node *ASTNode) *ASTNode {
 	if node == nil {
-		p.addDiagnostic(DiagnosticError, "Expected EOF, got }", brace.Token.Location)
-		return
+		return nil
 	}
-	if node.HasChild(TokenBraceOpen) {
-		node.AddChild(brace)
-	} else {
-		p.addClosingBraces(node.Parent, brace)
+	if node.Kind == NodeAssignment {
+		if len(node.Children) < 2 {
+			return nil
+		}
+		return node.Children[len(node.Children)-1]
+	}
+	return node
+}
+
+func withoutComments(tokens []*Token) []*Token {
+	result := make([]*Token, 0, len(tokens))
+	for _, t := range tokens {
+		if t.Type != TokenComment {
+			result = append(result, t)
+		}
 	}
+	return result
 }
 
 func (p *Parser) readLine() []*Token {
@@ -259,11 +333,13 @@ func (p *Parser) hasTokens() bool {
 	return p.position < len(p.tokens)
 }
 
-func (p *Parser) addDiagnostic(severity DiagnosticSeverity, message string, location Location) {
-	fmt.Printf("%s %s %d:%d", severity, message, location.Line, location.Pos)
-	p.diagnostics = append(p.diagnostics, &Diagnostic{
+func (p *Parser) addDiagnostic(severity DiagnosticSeverity, message string, location Location) *Diagnostic {
+	logger.Printf("%s %s %d:%d", severity, message, location.Line, location.Pos)
+	d := &Diagnostic{
 		Severity: severity,
 		Message:  message,
 		Location: location,
-	})
+	}
+	p.diagnostics = append(p.diagnostics, d)
+	return d
 }
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"io"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -86,4 +88,57 @@ func TestParser(t *testing.T) {
 		assert.Empty(t, diagnostics)
 	})
 
+	t.Run("opening brace on its own line is attached to the previous statement", func(t *testing.T) {
+		sut := New(file, "workspace\n{\nmodel {\n}\n}", fake)
+		ast, diagnostics := sut.Parse()
+		assert.Equal(t, "(root  (workspace  ({  )(model  ({  )(}  ))(}  )))", ast.ToString())
+		if assert.Equal(t, 1, len(diagnostics)) {
+			assert.Equal(t, "Opening curly brace symbols ({) must be on the same line.", diagnostics[0].Message)
+		}
+	})
+	t.Run("parsing continues after errors", func(t *testing.T) {
+		sut := New(file, "}\nworkspace {\nmodel\n{\n}\nviews {\n}\n}\n}", fake)
+		ast, diagnostics := sut.Parse()
+		assert.Equal(t, "(root  (workspace  ({  )(model  ({  )(}  ))(views  ({  )(}  ))(}  )))", ast.ToString())
+		if assert.Equal(t, 3, len(diagnostics)) {
+			assert.Equal(t, "Expected EOF, got }", diagnostics[0].Message)
+			assert.Equal(t, "Opening curly brace symbols ({) must be on the same line.", diagnostics[1].Message)
+			assert.Equal(t, "Expected EOF, got }", diagnostics[2].Message)
+		}
+	})
+	t.Run("blocks without a statement are skipped", func(t *testing.T) {
+		sut := New(file, "workspace {\n= {\nperson {\n}\n}\nmodel {\n}\n}", fake)
+		ast, diagnostics := sut.Parse()
+		assert.Equal(t, "(root  (workspace  ({  )(model  ({  )(}  ))(}  )))", ast.ToString())
+		if assert.Equal(t, 1, len(diagnostics)) {
+			assert.Equal(t, "Unexpected {", diagnostics[0].Message)
+		}
+	})
+	t.Run("closing brace on the same line closes the block", func(t *testing.T) {
+		sut := New(file, "workspace {\nmodel { }\nviews {\n}\n}", fake)
+		ast, diagnostics := sut.Parse()
+		assert.Equal(t, "(root  (workspace  ({  )(model  ({  )(}  ))(views  ({  )(}  ))(}  )))", ast.ToString())
+		if assert.Equal(t, 1, len(diagnostics)) {
+			assert.Equal(t, "Closing curly brace symbols (}) must be on a line of their own.", diagnostics[0].Message)
+		}
+	})
+	t.Run("diagnostics are not written to stdout", func(t *testing.T) {
+		// stdout is the channel of the language server
+		r, w, _ := os.Pipe()
+		stdout := os.Stdout
+		os.Stdout = w
+		sut := New(file, "workspace {\nmodel { }\n", fake)
+		_, diagnostics := sut.Parse()
+		os.Stdout = stdout
+		_ = w.Close()
+		written, _ := io.ReadAll(r)
+		assert.Equal(t, 2, len(diagnostics))
+		assert.Empty(t, string(written))
+	})
+	t.Run("subsequent assignments are siblings", func(t *testing.T) {
+		sut := New(file, "a = person\nb = person", fake)
+		ast, diagnostics := sut.Parse()
+		assert.Equal(t, "(root  (=  (a  )(person  ))(=  (b  )(person  )))", ast.ToString())
+		assert.Empty(t, diagnostics)
+	})
 }
@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RemoteIncluder fetches http(s) includes and caches them on disk keyed by their URL, any other path is delegated to
+// the fallback includer. Fetched files are kept in memory for a while, so analysing on every change does not wait
+// on the network.
+type RemoteIncluder struct {
+	fallback Includer
+	client   *http.Client
+	options  RemoteOptions
+	mu       sync.Mutex
+	fetched  map[string]fetchedFile
+}
+
+type fetchedFile struct {
+	content string
+	at      time.Time
+}
+
+type RemoteOptions struct {
+	// Timeout of a single request
+	Timeout time.Duration
+	// Directory to store the fetched files in, caching is disabled when empty
+	CacheDir string
+	// Serve includes from the cache only without touching the network
+	Offline bool
+	// How long a fetched file is used without revalidating it, every include is revalidated when zero
+	TTL time.Duration
+}
+
+func DefaultRemoteOptions() RemoteOptions {
+	options := RemoteOptions{Timeout: 10 * time.Second, TTL: 5 * time.Minute}
+	if dir, err := os.UserCacheDir(); err == nil {
+		options.CacheDir = filepath.Join(dir, "structurizr-lsp")
+	}
+	return options
+}
+
+func NewRemoteIncluder(fallback Includer, options RemoteOptions) *RemoteIncluder {
+	return &RemoteIncluder{fallback: fallback, client: &http.Client{Timeout: options.Timeout}, options: options, fetched: make(map[string]fetchedFile)}
+}
+
+func (r *RemoteIncluder) Include(included string) ([]IncludedFile, error) {
+	if !isURL(included) {
+		return r.fallback.Include(included)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.fetched[included]; ok && time.Since(f.at) < r.options.TTL {
+		return []IncludedFile{{Path: included, Content: f.content}}, nil
+	}
+	content, err := r.fetch(included)
+	if err != nil {
+		return nil, err
+	}
+	r.fetched[included] = fetchedFile{content: content, at: time.Now()}
+	return []IncludedFile{{Path: included, Content: content}}, nil
+}
+
+// Documentation is read from local paths only.
+func (r *RemoteIncluder) Documentation(path string) ([]IncludedFile, error) {
+	if isURL(path) {
+		return nil, fmt.Errorf("remote documentation is not supported: %s", path)
+	}
+	return r.fallback.Documentation(path)
+}
+
+// Fetches the URL revalidating the cached copy with its ETag, in offline mode or when the network fails only the
+// cache is used.
+func (r *RemoteIncluder) fetch(location string) (string, error) {
+	cached, etag, cacheErr := r.readCache(location)
+	if r.options.Offline {
+		if cacheErr != nil {
+			return "", fmt.Errorf("%s is not cached and offline mode is enabled", location)
+		}
+		return cached, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, location, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	if cacheErr == nil && etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		if cacheErr == nil {
+			logger.Printf("Failed to fetch %s, using the cached copy cause: %s", location, err)
+			return cached, nil
+		}
+		return "", fmt.Errorf("failed to fetch: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && cacheErr == nil {
+		return cached, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch: %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := r.writeCache(location, string(body), resp.Header.Get("ETag")); err != nil {
+		logger.Printf("Failed to cache %s cause: %s", location, err)
+	}
+	return string(body), nil
+}
+
+func (r *RemoteIncluder) readCache(location string) (string, string, error) {
+	if r.options.CacheDir == "" {
+		return "", "", fmt.Errorf("cache disabled")
+	}
+	key := cacheKey(location)
+	content, err := os.ReadFile(filepath.Join(r.options.CacheDir, key+".dsl"))
+	if err != nil {
+		return "", "", err
+	}
+	etag, _ := os.ReadFile(filepath.Join(r.options.CacheDir, key+".etag"))
+	return string(content), string(etag), nil
+}
+
+func (r *RemoteIncluder) writeCache(location, content, etag string) error {
+	if r.options.CacheDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(r.options.CacheDir, 0755); err != nil {
+		return err
+	}
+	key := cacheKey(location)
+	if err := os.WriteFile(filepath.Join(r.options.CacheDir, key+".dsl"), []byte(content), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.options.CacheDir, key+".etag"), []byte(etag), 0644)
+}
+
+func cacheKey(location string) string {
+	sum := sha256.Sum256([]byte(location))
+	return hex.EncodeToString(sum[:])
+}
+
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// Resolves a path of a directive like !include relative to its source, paths within remote files resolve against
+// their URL.
+func ResolvePath(source, path string) string {
+	if isURL(path) {
+		return path
+	}
+	if isURL(source) {
+		base, err := url.Parse(source)
+		if err != nil {
+			return path
+		}
+		ref, err := url.Parse(path)
+		if err != nil {
+			return path
+		}
+		return base.ResolveReference(ref).String()
+	}
+	return filepath.Join(filepath.Dir(source), path)
+}
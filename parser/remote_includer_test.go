@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteIncluder(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/model.dsl":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte("person \"User\""))
+		case "/nested/workspace.dsl":
+			_, _ = w.Write([]byte("!include people.dsl"))
+		case "/nested/people.dsl":
+			_, _ = w.Write([]byte("person \"Remote\""))
+		case "/slow.dsl":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	options := func() RemoteOptions {
+		return RemoteOptions{Timeout: time.Second, CacheDir: t.TempDir()}
+	}
+	t.Run("fetches urls", func(t *testing.T) {
+		sut := NewRemoteIncluder(&FakeIncluder{}, options())
+		files, err := sut.Include(server.URL + "/model.dsl")
+		assert.NoError(t, err)
+		assert.Equal(t, []IncludedFile{{Path: server.URL + "/model.dsl", Content: "person \"User\""}}, files)
+	})
+	t.Run("delegates local paths to the fallback", func(t *testing.T) {
+		sut := NewRemoteIncluder(&FakeIncluder{}, options())
+		files, err := sut.Include("/ws/test.dsl")
+		assert.NoError(t, err)
+		assert.Equal(t, "user \"Person\"", files[0].Content)
+	})
+	t.Run("revalidates the cached copy with its etag", func(t *testing.T) {
+		sut := NewRemoteIncluder(&FakeIncluder{}, options())
+		_, _ = sut.Include(server.URL + "/model.dsl")
+		requests.Store(0)
+		files, err := sut.Include(server.URL + "/model.dsl")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), requests.Load())
+		assert.Equal(t, "person \"User\"", files[0].Content)
+	})
+	t.Run("offline mode serves the cache without requests", func(t *testing.T) {
+		opts := options()
+		_, _ = NewRemoteIncluder(&FakeIncluder{}, opts).Include(server.URL + "/model.dsl")
+		opts.Offline = true
+		requests.Store(0)
+		files, err := NewRemoteIncluder(&FakeIncluder{}, opts).Include(server.URL + "/model.dsl")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(0), requests.Load())
+		assert.Equal(t, "person \"User\"", files[0].Content)
+	})
+	t.Run("fetched files are kept for the ttl", func(t *testing.T) {
+		opts := options()
+		opts.TTL = time.Minute
+		sut := NewRemoteIncluder(&FakeIncluder{}, opts)
+		_, _ = sut.Include(server.URL + "/model.dsl")
+		requests.Store(0)
+		files, err := sut.Include(server.URL + "/model.dsl")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(0), requests.Load())
+		assert.Equal(t, "person \"User\"", files[0].Content)
+	})
+	t.Run("the cached copy is served when the network fails", func(t *testing.T) {
+		unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("person \"User\""))
+		}))
+		sut := NewRemoteIncluder(&FakeIncluder{}, options())
+		_, _ = sut.Include(unreachable.URL + "/model.dsl")
+		unreachable.Close()
+		files, err := sut.Include(unreachable.URL + "/model.dsl")
+		assert.NoError(t, err)
+		assert.Equal(t, "person \"User\"", files[0].Content)
+	})
+	t.Run("offline mode fails for uncached urls", func(t *testing.T) {
+		opts := options()
+		opts.Offline = true
+		_, err := NewRemoteIncluder(&FakeIncluder{}, opts).Include(server.URL + "/model.dsl")
+		assert.Error(t, err)
+	})
+	t.Run("failing requests are errors", func(t *testing.T) {
+		_, err := NewRemoteIncluder(&FakeIncluder{}, options()).Include(server.URL + "/missing.dsl")
+		assert.EqualError(t, err, "failed to fetch: 404 Not Found")
+	})
+	t.Run("requests time out", func(t *testing.T) {
+		opts := options()
+		opts.Timeout = 50 * time.Millisecond
+		_, err := NewRemoteIncluder(&FakeIncluder{}, opts).Include(server.URL + "/slow.dsl")
+		assert.Error(t, err)
+	})
+	t.Run("relative includes of remote files resolve against their url", func(t *testing.T) {
+		sut := NewRemoteIncluder(&FakeIncluder{}, options())
+		tokens, graph, diagnostics := LexWithIncludes("/ws/workspace.dsl", "!include "+server.URL+"/nested/workspace.dsl", sut)
+		assert.Empty(t, diagnostics)
+		assert.Equal(t, []string{"/ws/workspace.dsl", server.URL + "/nested/workspace.dsl", server.URL + "/nested/people.dsl"}, graph.Files())
+		assert.Equal(t, "Remote", tokens[7].Content)
+		assert.Equal(t, server.URL+"/nested/people.dsl", tokens[7].Location.Source)
+	})
+	t.Run("fetch failures are reported on the include", func(t *testing.T) {
+		sut := NewRemoteIncluder(&FakeIncluder{}, options())
+		_, _, diagnostics := LexWithIncludes("/ws/workspace.dsl", "workspace {\n!include "+server.URL+"/missing.dsl\n}", sut)
+		if assert.Equal(t, 1, len(diagnostics)) {
+			assert.Equal(t, "Failed to include "+server.URL+"/missing.dsl: failed to fetch: 404 Not Found", diagnostics[0].Message)
+			assert.Equal(t, Location{Source: "/ws/workspace.dsl", Line: 1, Pos: 0}, diagnostics[0].Location)
+		}
+	})
+}
@@ -1,15 +1,45 @@
 package parser
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
 	"sync"
 )
 
 type SemanticAnalyser struct {
 	parser      *Parser
+	includer    Includer
 	diagnostics []*Diagnostic
 	ws          *Workspace
 	mu          sync.Mutex
+	// chain of workspaces extended by the analysed one, to detect cycles
+	extending     []string
+	hierarchical  bool
+	relationships []*pendingRelationship
+	// strategy of the implied relationships, which applies to the relationships following the directive
+	implied string
+	// !relationship directives refer to relationships which are resolved at the end of the model, expressions of
+	// !elements and !relationships are evaluated after them
+	relationshipDirectives []*ASTNode
+	bulkDirectives         []*bulkDirective
+}
+
+// Relationships are resolved once every element of the model is known.
+type pendingRelationship struct {
+	node    *RelationshipNode
+	scope   *Element
+	implied string
+}
+
+// !elements and !relationships directives are evaluated within the scope of their enclosing element.
+type bulkDirective struct {
+	node  *ASTNode
+	scope *Element
 }
 
 func (s *SemanticAnalyser) Analyse() (*Workspace, *ASTNode, []*Diagnostic) {
@@ -21,24 +51,29 @@ func (s *SemanticAnalyser) Analyse() (*Workspace, *ASTNode, []*Diagnostic) {
 	return s.ws, ast, s.diagnostics
 }
 
+// Returns the files included by the analysed source.
+func (s *SemanticAnalyser) IncludeGraph() *IncludeGraph {
+	return s.parser.IncludeGraph()
+}
+
 func (s *SemanticAnalyser) walk(node *ASTNode) {
 	if node == nil {
 		return
 	}
-	if node.Token.Content == "root" {
+	if node.Kind == NodeRoot {
 		s.visitRoot(node)
 	}
 }
 
-func NewAnalyser(sourceFile string, content string) *SemanticAnalyser {
-	p := New(sourceFile, content, NewIncluder())
-	return &SemanticAnalyser{parser: p}
+func NewAnalyser(sourceFile string, content string, in Includer) *SemanticAnalyser {
+	p := New(sourceFile, content, in)
+	return &SemanticAnalyser{parser: p, includer: in}
 }
 
 func (s *SemanticAnalyser) visitRoot(node *ASTNode) {
 	// required children workspace
 	for _, c := range node.Children {
-		if c.Token.Content == "workspace" {
+		if c.Kind == NodeWorkspace {
 			s.visitWorkspace(c)
 		}
 	}
@@ -50,24 +85,41 @@ func (s *SemanticAnalyser) visitRoot(node *ASTNode) {
 func (s *SemanticAnalyser) visitWorkspace(node *ASTNode) {
 	logger.Println("visitWorkspace")
 	s.ws = &Workspace{}
+	var base *Workspace
+	if path := (&WorkspaceNode{node}).Base(); path != nil {
+		s.ws.Extends = path.Content
+		base = s.visitBase(path)
+	}
+	if base != nil && base.Model != nil && len(base.Model.Archetypes) > 0 {
+		// the parser did not know the archetypes of the base workspace
+		reclassify(node, base.Model.elementArchetypes())
+	}
+	s.hierarchical = identifierMode(node) == "hierarchical"
+	s.implied = ImpliedRelationshipsUnlessAnyExists
+	var views *ASTNode
 	for _, c := range node.Children {
-		if isKeyWordWithName(c, "model") {
-			s.visitModel(c)
-		} else if isKeyWordWithName(c, "views") {
-			s.visitViews(c)
-		} else if isKeyWordWithName(c, "name") {
+		if c.Kind == NodeModel {
+			s.visitModel(c, base)
+		} else if c.Kind == NodeViews {
+			// views refer to the elements of the model, which may be defined after them
+			views = c
+		} else if is(c, NodeStatement, "name") {
 			s.ws.Name = s.visitAttribute(c)
-		} else if isKeyWordWithName(c, "properties") {
+		} else if is(c, NodeProperties, "properties") {
 			s.ws.Properties = s.visitProperties(c)
-		} else if isKeyWordWithName(c, "description") {
+		} else if is(c, NodeStatement, "description") {
 			s.ws.Description = s.visitAttribute(c)
-		} else if isKeyWordWithName(c, "!identifiers") {
+		} else if is(c, NodeDirective, "!identifiers") {
 			s.ws.Identifiers = s.visitOptionWithPossibleValues(c, "flat", "hierarchical")
-		} else if isKeyWordWithName(c, "!docs") {
-			s.ws.Docs = s.visitDocs(c)
-		} else if isKeyWordWithName(c, "!adrs") {
-			s.ws.Adrs = s.visitAdrs(c)
-		} else if isKeyWordWithName(c, "configuration") {
+		} else if is(c, NodeDirective, "!impliedRelationships") {
+			s.visitImpliedRelationships(c)
+		} else if is(c, NodeDirective, "!docs") {
+			s.ws.Docs = s.visitDocs(&DirectiveNode{c})
+		} else if is(c, NodeDirective, "!adrs") {
+			s.ws.Adrs = s.visitAdrs(&DirectiveNode{c})
+		} else if is(c, NodeDirective, "!include") {
+			continue // included statements are siblings of the directive
+		} else if c.Kind == NodeConfiguration {
 			s.ws.Configuration = s.visitConfiguration(c)
 		} else if isBraces(c) {
 			continue
@@ -75,46 +127,160 @@ func (s *SemanticAnalyser) visitWorkspace(node *ASTNode) {
 			s.addWarning("Unexpected children: "+c.Token.Content, c)
 		}
 	}
-	AugmentAttributes(node)
+	if s.ws.Model == nil && base != nil {
+		s.ws.Model = base.Model
+	}
+	if views != nil {
+		s.visitViews(views, base)
+	} else if base != nil {
+		s.ws.views = base.views
+	}
+	if s.ws.Extends != "" {
+		// the model and the views are inherited
+		return
+	}
 	if s.ws.Model == nil {
-		s.addWarning("Workspace must contain a model", node)
+		s.addWarning("Workspace must contain a model", node).Code = CodeMissingModel
 	}
 	if s.ws.views == nil {
-		s.addWarning("Workspace must contain views", node)
+		s.addWarning("Workspace must contain views", node).Code = CodeMissingViews
+	}
+}
+
+// Analyses the workspace extended by the analysed one, which is either a DSL or a JSON workspace.
+func (s *SemanticAnalyser) visitBase(path *Token) *Workspace {
+	source := s.parser.IncludeGraph().Root
+	fullpath := ResolvePath(source, path.Content)
+	if fullpath == source || slices.Contains(s.extending, fullpath) {
+		s.addError("Cyclic extension of "+fullpath, path.Location)
+		return nil
+	}
+	files, err := s.includer.Include(fullpath)
+	if err == nil && len(files) != 1 {
+		err = errors.New("expected a single workspace file")
+	}
+	if err != nil {
+		s.addError(fmt.Sprintf("Failed to extend %s: %s", path.Content, err), path.Location)
+		return nil
+	}
+	file := files[0]
+	var base *Workspace
+	if strings.HasSuffix(strings.ToLower(file.Path), ".json") {
+		s.parser.graph.base = newIncludeGraph(file.Path)
+		base, err = workspaceFromJSON(file.Path, file.Content)
+	} else {
+		analyser := &SemanticAnalyser{parser: New(file.Path, file.Content, s.includer), includer: s.includer, extending: append(slices.Clone(s.extending), source)}
+		ws, _, diags := analyser.Analyse()
+		s.parser.graph.base = analyser.IncludeGraph()
+		base = ws
+		if ws == nil {
+			err = errors.New("no workspace found")
+		} else if slices.ContainsFunc(diags, func(d *Diagnostic) bool { return d.Severity == DiagnosticError }) {
+			s.addError(fmt.Sprintf("Extended workspace %s contains errors", path.Content), path.Location)
+		}
+	}
+	if err != nil {
+		s.addError(fmt.Sprintf("Failed to extend %s: %s", path.Content, err), path.Location)
+		return nil
+	}
+	return base
+}
+
+// Returns the value of the !identifiers directive among the children of the node.
+func identifierMode(node *ASTNode) string {
+	for _, c := range node.Children {
+		if is(c, NodeDirective, "!identifiers") && len(c.Attributes) > 0 {
+			return c.Attributes[0].Content
+		}
 	}
+	return ""
 }
 
 func isBraces(node *ASTNode) bool {
-	return node.Token.Type == TokenBraceClose || TokenBraceOpen == node.Token.Type
+	return node.Kind == NodeBlockStart || node.Kind == NodeBlockEnd
 }
 
-func (s *SemanticAnalyser) visitDocs(node *ASTNode) *Documentation {
+// Documentation is either a single file or a directory relative to the file of the directive.
+func (s *SemanticAnalyser) visitDocs(node *DirectiveNode) *Documentation {
 	docs := &Documentation{}
-	if len(node.Attributes) > 0 && node.Attributes[0].Type == TokenKeyword {
-		docs.Path = node.Attributes[0].Content
+	path := node.Path()
+	if isValue(path) {
+		docs.Path = path.Content
 	}
-	if len(node.Attributes) > 1 && node.Attributes[1].Type == TokenKeyword {
-		docs.Fqcn = node.Attributes[1].Content
+	if importer := node.Importer(); isValue(importer) {
+		docs.Fqcn = importer.Content
+		if !fqcnPattern.MatchString(importer.Content) {
+			s.addErrorRange("Invalid importer "+importer.Content+", expected a fully qualified class name", tokenRange(importer))
+		}
 	}
+	if docs.Path == "" {
+		s.addWarning("Expected a path for "+node.Content, node)
+		return docs
+	}
+	s.documentationPath(path)
 	return docs
 }
 
-func (s *SemanticAnalyser) visitAdrs(node *ASTNode) *ADR {
+// Decisions are read from the directory with adr-tools, MADR, log4brains or a custom importer.
+func (s *SemanticAnalyser) visitAdrs(node *DirectiveNode) *ADR {
 	adrs := &ADR{}
-	if len(node.Attributes) > 0 && node.Attributes[0].Type == TokenKeyword {
-		adrs.Path = node.Attributes[0].Content
+	path := node.Path()
+	if isValue(path) {
+		adrs.Path = path.Content
+	}
+	importer := "adrtools"
+	if token := node.Importer(); isValue(token) {
+		adrs.Fqcn = token.Content
+		known := false
+		if importer, known = adrImporter(token.Content); !known {
+			s.addErrorRange("Unknown importer "+token.Content+", expected adrtools, madr, log4brains or a fully qualified class name", tokenRange(token))
+			return adrs
+		}
+	}
+	if adrs.Path == "" {
+		s.addWarning("Expected a path for "+node.Content, node)
+		return adrs
+	}
+	files, ok := s.documentationPath(path)
+	if !ok || adrImporters[importer] == "" {
+		// custom importers read the decisions their own way
+		return adrs
+	}
+	decisions, problems := readDecisions(importer, files)
+	adrs.Decisions = decisions
+	for _, problem := range problems {
+		s.addWarning(problem, node)
 	}
-	if len(node.Attributes) > 1 && node.Attributes[1].Type == TokenKeyword {
-		adrs.Fqcn = node.Attributes[1].Content
+	if len(decisions) == 0 {
+		s.addWarning("No ADRs found in "+path.Content, node)
 	}
 	return adrs
 }
 
-func isKeyWordWithName(node *ASTNode, name string) bool {
-	if node.Token.Type == TokenKeyword && node.Token.Content == name {
-		return true
+// Reports whether the token is a non-empty value, paths and class names may be quoted or not.
+func isValue(t *Token) bool {
+	return t != nil && (t.Type == TokenKeyword || t.Type == TokenString) && t.Content != ""
+}
+
+// Reads the documentation path relative to the file of the directive with the includer, false if it does not exist
+// or it is remote. The analysis depends on the path like on included files.
+func (s *SemanticAnalyser) documentationPath(path *Token) ([]IncludedFile, bool) {
+	resolved := ResolvePath(path.Location.Source, path.Content)
+	if isURL(resolved) {
+		return nil, false
 	}
-	return false
+	s.parser.graph.addDocumentation(resolved)
+	files, err := s.includer.Documentation(resolved)
+	if err != nil {
+		s.addErrorRange("Path "+path.Content+" does not exist", tokenRange(path))
+		return nil, false
+	}
+	return files, true
+}
+
+// Reports whether the node is of the given kind and starts with the keyword.
+func is(node *ASTNode, kind NodeKind, keyword string) bool {
+	return node.Kind == kind && node.Token.Content == keyword
 }
 
 func (s *SemanticAnalyser) visitOptionWithPossibleValues(node *ASTNode, possibleValues ...string) string {
@@ -125,7 +291,8 @@ func (s *SemanticAnalyser) visitOptionWithPossibleValues(node *ASTNode, possible
 			}
 		}
 	}
-	s.addWarning(fmt.Sprintf("Invalid option, possible values %s", possibleValues), node)
+	d := s.addWarning(fmt.Sprintf("Invalid option, possible values %s", possibleValues), node)
+	d.Code, d.Data = CodeInvalidOption, possibleValues
 	return ""
 }
 
@@ -136,121 +303,661 @@ func (s *SemanticAnalyser) visitAttribute(node *ASTNode) string {
 	return ""
 }
 
-func AugmentAttributes(node *ASTNode) {
-	if len(node.Attributes) > 0 && node.Attributes[0].Type == TokenString {
-		node.Attributes[0].Type = TokenName
-	}
-	if len(node.Attributes) > 1 && node.Attributes[1].Type == TokenString {
-		node.Attributes[1].Type = TokenDescription
-	}
-	if len(node.Attributes) > 2 && node.Attributes[2].Type == TokenString {
-		node.Attributes[2].Type = TokenTags
+// Returns the name of an element, elements are still analysed when the name is missing during editing.
+func (s *SemanticAnalyser) visitName(node *ASTNode) string {
+	if name := node.Attribute(RoleName); name != nil {
+		return name.Content
 	}
+	s.addWarning("Expected a name for "+node.Token.Content, node)
+	return ""
+}
+
+func (s *SemanticAnalyser) addWarning(message string, node Node) *Diagnostic {
+	d := &Diagnostic{Message: message, Severity: DiagnosticWarning, Location: node.AST().Location}
+	s.diagnostics = append(s.diagnostics, d)
+	return d
 }
 
-func (s *SemanticAnalyser) addWarning(message string, node *ASTNode) {
-	s.diagnostics = append(s.diagnostics, &Diagnostic{Message: message, Severity: DiagnosticWarning, Location: node.Location})
+func (s *SemanticAnalyser) addError(message string, location Location) *Diagnostic {
+	d := &Diagnostic{Message: message, Severity: DiagnosticError, Location: location}
+	s.diagnostics = append(s.diagnostics, d)
+	return d
 }
 
-func (s *SemanticAnalyser) visitViews(node *ASTNode) {
+func (s *SemanticAnalyser) addErrorRange(message string, rng Range) *Diagnostic {
+	d := &Diagnostic{Message: message, Severity: DiagnosticError, Location: rng.Start, End: rng.End}
+	s.diagnostics = append(s.diagnostics, d)
+	return d
+}
+
+// Views of the extended workspace are kept and the views of the block are added to them.
+func (s *SemanticAnalyser) visitViews(node *ASTNode, base *Workspace) {
 	logger.Println("visitViews")
+	views := &ViewSet{Views: make([]*View, 0)}
+	if base != nil && base.views != nil {
+		views.Views = append(views.Views, base.views.Views...)
+	}
+	// filtered views may be based on views defined after them
+	filtered := make([]*View, 0)
+	filteredNodes := make([]*ASTNode, 0)
 	for _, c := range node.Children {
 		logger.Println(c.Token.Content)
-		if c.Token.Content == "properties" && c.Token.Type == TokenKeyword {
+		if is(c, NodeProperties, "properties") {
 			s.visitProperties(c)
+		} else if c.Kind == NodeView {
+			v := s.visitView(&ViewNode{c})
+			views.Views = append(views.Views, v)
+			if c.Content == "filtered" {
+				filtered, filteredNodes = append(filtered, v), append(filteredNodes, c)
+			}
+		}
+	}
+	for i, v := range filtered {
+		s.visitFilteredView(v, filteredNodes[i], views)
+	}
+	s.ws.views = views
+}
+
+// Resolves the elements and relationships referred by a view.
+func (s *SemanticAnalyser) visitView(node *ViewNode) *View {
+	v := &View{Type: node.ViewType(), Key: content(node.Key()), Environment: content(node.Environment()), Range: node.Range}
+	if s.ws.Model == nil {
+		return v
+	}
+	// the scope of filtered views is the key of another view
+	if scope := node.Scope(); scope != nil && scope.Content != "*" && node.ViewType() != "filtered" {
+		v.Scope = s.resolveElement(scope, nil)
+	}
+	for _, c := range node.Children {
+		if c.Kind == NodeRelationship {
+			// steps of dynamic views refer to existing relationships
+			step := &viewStep{}
+			relationship := &RelationshipNode{c}
+			if a := relationship.Source(); a != nil {
+				step.source = s.resolveElement(a, nil)
+			}
+			if a := relationship.Destination(); a != nil {
+				step.destination = s.resolveElement(a, nil)
+			}
+			// implied relationships may be used as well
+			if step.source != nil && step.destination != nil && !s.ws.Model.hasRelationship(step.source, step.destination, false, "") {
+				s.addWarning(fmt.Sprintf("A relationship between %s and %s does not exist in model", step.source.Name, step.destination.Name), c)
+			}
+			v.steps = append(v.steps, step)
+		} else if is(c, NodeStatement, "include") || is(c, NodeStatement, "exclude") {
+			for _, a := range c.Attributes {
+				if st := s.visitViewExpression(a); st != nil {
+					st.include = c.Content == "include"
+					v.statements = append(v.statements, st)
+				}
+			}
 		}
 	}
-	s.ws.views = &ViewSet{}
+	return v
+}
+
+// Parses an expression of an include or exclude statement.
+func (s *SemanticAnalyser) visitViewExpression(token *Token) *viewStatement {
+	if token.Content == "*" {
+		return &viewStatement{wildcard: true}
+	}
+	expr := s.parseExpression(token, nil)
+	if expr == nil {
+		return nil
+	}
+	return &viewStatement{expression: expr}
+}
+
+// Filtered views show the elements and relationships of their base view with or without the given tags.
+func (s *SemanticAnalyser) visitFilteredView(v *View, node *ASTNode, views *ViewSet) {
+	key := node.Attribute(RoleScope)
+	if key == nil {
+		s.addWarning("Expected the key of the base view", node)
+		return
+	}
+	base := views.View(key.Content)
+	if base == nil {
+		s.addError("Unknown view "+key.Content, key.Location)
+		return
+	}
+	if base.Type == "filtered" {
+		s.addError("Filtered views cannot be based on filtered views", key.Location)
+		return
+	}
+	v.base = base
+	v.excludeTags = strings.EqualFold(attributeContent(node, RoleOption), "exclude")
+	v.tags = splitTags(attributeContent(node, RoleTags))
 }
 
 func (s *SemanticAnalyser) visitProperties(node *ASTNode) map[string]string {
 	logger.Println("visitProperties")
 	props := make(map[string]string)
 	for _, c := range node.Children {
-		if c.Token.Type == TokenString {
-			c.Token.Type = TokenName
-			if len(c.Attributes) > 0 && c.Attributes[0].Type == TokenString {
-				c.Attributes[0].Type = TokenValue
-				props[c.Token.Content] = c.Attributes[0].Content
+		if c.Kind == NodeProperty && c.Token.Type == TokenString {
+			if value := c.Attribute(RoleValue); value != nil && value.Type == TokenString {
+				props[c.Token.Content] = value.Content
 			}
 		}
 	}
 	return props
 }
 
-func (s *SemanticAnalyser) visitModel(node *ASTNode) {
+func (s *SemanticAnalyser) visitModel(node *ASTNode, base *Workspace) {
 	logger.Println("visitModel")
-	model := &Model{
-		People:                 make(map[string]*Person),
-		Groups:                 make(map[string]*Group),
-		References:             make(map[string]interface{}),
-		SoftwareSystems:        make(map[string]*SoftwareSystem),
-		DeploymentEnvironments: make(map[string]*DeploymentEnvironment),
+	model := newModel()
+	if base != nil && base.Model != nil {
+		model.inherit(base.Model)
 	}
+	model.ImpliedRelationships = s.implied
+	s.ws.Model = model
 	for _, c := range node.Children {
-		if c.Token.Content == "person" {
+		if is(c, NodeElement, "person") {
 			person := s.visitPerson(c)
 			model.People[person.Name] = person
-		} else if isKeyWordWithName(c, "!identifiers") {
+		} else if is(c, NodeDirective, "!identifiers") {
 			model.Identifiers = s.visitOptionWithPossibleValues(c, "flat", "hierarchical")
 		} else if isAssignment(c, "person") {
 			person := s.visitPerson(c.Children[1])
 			identifier := getIdentifier(c)
 			model.References[identifier] = person
 			model.People[person.Name] = person
-		} else if isKeyWordWithName(c, "group") {
+		} else if is(c, NodeElement, "group") {
 			model.Groups[fmt.Sprintf("%p", &c)] = s.visitGroup(c)
-		} else if isKeyWordWithName(c, "softwareSystem") {
+		} else if is(c, NodeElement, "softwareSystem") {
 			ss := s.visitSoftwareSystem(c)
 			model.SoftwareSystems[ss.Name] = ss
-		} else if isKeyWordWithName(c, "deploymentEnvironment") {
+		} else if is(c, NodeElement, "deploymentEnvironment") {
 			de := s.visitDeploymentEnvironment(c)
 			model.DeploymentEnvironments[de.Name] = de
 		}
 	}
-	s.ws.Model = model
+	if mode := identifierMode(node); mode != "" {
+		s.hierarchical = mode == "hierarchical"
+	}
+	s.visitElements(node, nil)
+	for _, r := range s.relationships {
+		s.visitRelationship(r)
+	}
+	for _, d := range s.relationshipDirectives {
+		s.visitRelationshipDirective(&DirectiveNode{d})
+	}
+	for _, d := range s.bulkDirectives {
+		s.visitBulkDirective(&DirectiveNode{d.node}, d.scope)
+	}
+	s.relationships = nil
+	s.relationshipDirectives = nil
+	s.bulkDirectives = nil
+}
+
+// Defines the elements and collects the relationships of a block, groups only contribute their children.
+func (s *SemanticAnalyser) visitElements(node *ASTNode, parent *Element) {
+	for _, c := range node.Children {
+		if c.Kind == NodeAssignment && len(c.Children) > 1 {
+			s.visitModelStatement(c.Children[1], c.Children[0], parent)
+		} else {
+			s.visitModelStatement(c, nil, parent)
+		}
+	}
+}
+
+func (s *SemanticAnalyser) visitModelStatement(node *ASTNode, identifier *ASTNode, parent *Element) {
+	switch {
+	case node.Kind == NodeRelationship:
+		s.relationships = append(s.relationships, &pendingRelationship{node: &RelationshipNode{node}, scope: parent, implied: s.implied})
+	case node.Kind == NodeElement && transparentElements[node.Content]:
+		s.visitElements(node, parent)
+	case node.Kind == NodeElement:
+		s.visitElement(&ElementNode{node}, parent)
+	case node.Kind == NodeDirective && elementDirectives[node.Content]:
+		s.visitElementDirective(&DirectiveNode{node}, identifier, parent)
+	case node.Kind == NodeArchetypes:
+		s.visitArchetypes(node)
+	case is(node, NodeDirective, "!impliedRelationships"):
+		s.visitImpliedRelationships(node)
+	case is(node, NodeDirective, "!relationship"):
+		s.relationshipDirectives = append(s.relationshipDirectives, node)
+	case is(node, NodeDirective, "!elements") || is(node, NodeDirective, "!relationships"):
+		s.bulkDirectives = append(s.bulkDirectives, &bulkDirective{node: node, scope: parent})
+	case node.Kind == NodeStatement && (identifier != nil || parent == nil):
+		// statements within elements amend them, anything else should have been an element
+		s.addError("Unknown element type or archetype "+node.Content, node.Location)
+	}
+}
+
+// Archetypes are element or relationship types with default details, they may be based on other archetypes.
+func (s *SemanticAnalyser) visitArchetypes(node *ASTNode) {
+	for _, c := range node.Children {
+		if c.Kind != NodeAssignment || len(c.Children) < 2 {
+			continue
+		}
+		identifier, definition := c.Children[0], c.Children[1]
+		a := &Archetype{Name: identifier.Content, Definition: tokenRange(&identifier.Token), Details: Details{}.clone()}
+		base := ""
+		switch {
+		case definition.Kind == NodeRelationship:
+			a.Type = "->"
+			base = relationshipArchetype(definition.Content)
+		case definition.Kind == NodeElement && elementRoles[definition.Content] != nil:
+			a.Type = definition.Content
+		case definition.Kind == NodeElement:
+			base = definition.Content
+		default:
+			s.addError("Unknown archetype "+definition.Content, definition.Location)
+			continue
+		}
+		if base != "" {
+			if a.Base = s.resolveArchetype(&definition.Token, base); a.Base == nil {
+				continue
+			}
+			a.Type, a.Metadata, a.Details = a.Base.Type, a.Base.Metadata, a.Base.Details.clone()
+		}
+		s.visitDetails(definition, &a.Details)
+		for _, m := range definition.Children {
+			if is(m, NodeStatement, "metadata") {
+				a.Metadata = s.visitAttribute(m)
+			}
+		}
+		if s.ws.Model.Archetype(a.Name) != nil {
+			s.addError("Duplicate archetype "+a.Name, identifier.Location)
+		}
+		s.ws.Model.Archetypes[strings.ToLower(a.Name)] = a
+		s.addReference(&Reference{Range: a.Definition, Identifier: a.Name, Archetype: a, Definition: true})
+	}
+}
+
+// Returns the archetype of the keyword or the relationship operator, unknown archetypes are reported.
+func (s *SemanticAnalyser) resolveArchetype(token *Token, name string) *Archetype {
+	a := s.ws.Model.Archetype(name)
+	if a == nil {
+		s.addError("Unknown archetype "+name, token.Location)
+		return nil
+	}
+	s.addReference(&Reference{Range: tokenRange(token), Identifier: name, Archetype: a})
+	return a
+}
+
+// Returns the archetype name of a relationship operator like --https->, empty for plain relationships.
+func relationshipArchetype(operator string) string {
+	if !strings.HasPrefix(operator, "--") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(operator, "--"), "->")
+}
+
+// Sets the strategy of the relationships following the directive.
+func (s *SemanticAnalyser) visitImpliedRelationships(node *ASTNode) {
+	if len(node.Attributes) == 0 {
+		s.addWarning("Expected a strategy for "+node.Content, node)
+		return
+	}
+	option := node.Attributes[0]
+	strategy, ok := impliedRelationshipsStrategy(option.Content)
+	if !ok {
+		s.addError("Unknown implied relationships strategy "+option.Content, option.Location)
+		return
+	}
+	s.implied = strategy
+	if s.ws.Model != nil {
+		s.ws.Model.ImpliedRelationships = strategy
+	}
+}
+
+// Amends every element or relationship selected by the expression of !elements and !relationships.
+func (s *SemanticAnalyser) visitBulkDirective(node *DirectiveNode, scope *Element) {
+	token := node.Expression()
+	if token == nil {
+		s.addWarning("Expected an expression for "+node.Content, node)
+		return
+	}
+	expr := s.parseExpression(token, scope)
+	if expr == nil {
+		return
+	}
+	if node.Content == "!elements" {
+		if !expr.SelectsElements() {
+			s.addErrorRange("Expected an element expression", tokenRange(token))
+			return
+		}
+		for _, e := range expr.Elements(s.ws.Model) {
+			s.visitDetails(node.ASTNode, &e.Details)
+		}
+		return
+	}
+	if expr.SelectsElements() {
+		s.addErrorRange("Expected a relationship expression", tokenRange(token))
+		return
+	}
+	for _, r := range expr.Relationships(s.ws.Model) {
+		s.visitDetails(node.ASTNode, &r.Details)
+	}
+}
+
+// Parses an expression resolving its identifiers in the scope, the resolved identifiers are references and problems
+// are reported at the failing part.
+func (s *SemanticAnalyser) parseExpression(token *Token, scope *Element) *Expression {
+	expr, err := ParseExpression(token, s.ws.Model, func(identifier string, rng Range) *Element {
+		e := s.lookup(identifier, scope)
+		if e != nil {
+			s.addReference(&Reference{Range: rng, Identifier: identifier, Element: e})
+		}
+		return e
+	})
+	if err != nil {
+		d := s.addErrorRange(err.Message, err.Range)
+		d.Code, d.Data = err.Code, err.Data
+		return nil
+	}
+	return expr
+}
+
+// Reopens an existing element, the block amends the element and may define its children and relationships.
+func (s *SemanticAnalyser) visitElementDirective(node *DirectiveNode, identifier *ASTNode, parent *Element) {
+	target := node.Target()
+	if target == nil {
+		s.addWarning("Expected an element for "+node.Content, node)
+		return
+	}
+	e := s.resolveTarget(target, parent)
+	if e == nil {
+		return
+	}
+	if identifier != nil {
+		// the identifier becomes an alias of the element
+		alias := s.qualify(identifier.Content, parent)
+		if s.ws.Model.Element(alias) != nil {
+			s.addError("Duplicate identifier "+identifier.Content, identifier.Location)
+		} else {
+			s.ws.Model.alias(alias, e)
+		}
+		s.addReference(&Reference{Range: tokenRange(&identifier.Token), Identifier: identifier.Content, Element: e, Definition: true})
+	}
+	s.visitDetails(node.ASTNode, &e.Details)
+	s.visitElements(node.ASTNode, e)
+}
+
+// Elements are reopened either by their identifier or by their canonical name, e.g. "SoftwareSystem://System".
+func (s *SemanticAnalyser) resolveTarget(target *Token, scope *Element) *Element {
+	if !strings.Contains(target.Content, "://") {
+		return s.resolveElement(target, scope)
+	}
+	e := s.ws.Model.ElementByCanonicalName(target.Content)
+	if e == nil {
+		s.addError("Unknown element "+target.Content, target.Location)
+		return nil
+	}
+	s.addReference(&Reference{Range: tokenRange(target), Identifier: target.Content, Element: e})
+	return e
+}
+
+// Reopens an existing relationship to amend it.
+func (s *SemanticAnalyser) visitRelationshipDirective(node *DirectiveNode) {
+	target := node.Target()
+	if target == nil {
+		s.addWarning("Expected a relationship for "+node.Content, node)
+		return
+	}
+	r := s.ws.Model.Relationship(target.Content)
+	if r == nil {
+		s.addError("Unknown relationship "+target.Content, target.Location)
+		return
+	}
+	s.addReference(&Reference{Range: tokenRange(target), Identifier: target.Content, Relationship: r})
+	s.visitDetails(node.ASTNode, &r.Details)
+}
+
+// Amends the details with the statements of a block, e.g. tags "Internal" or a properties block.
+func (s *SemanticAnalyser) visitDetails(node *ASTNode, d *Details) {
+	for _, c := range node.Children {
+		if is(c, NodeStatement, "tags") {
+			for _, a := range c.Attributes {
+				d.addTags(splitTags(a.Content)...)
+			}
+		} else if is(c, NodeStatement, "description") {
+			d.Description = s.visitAttribute(c)
+		} else if is(c, NodeStatement, "technology") {
+			d.Technology = s.visitAttribute(c)
+		} else if is(c, NodeStatement, "url") {
+			d.URL = s.visitURL(c)
+		} else if is(c, NodeProperties, "perspectives") {
+			for _, p := range s.visitPerspectives(c) {
+				d.addPerspective(p)
+			}
+		} else if is(c, NodeProperties, "properties") {
+			if d.Properties == nil {
+				d.Properties = make(map[string]string)
+			}
+			maps.Copy(d.Properties, s.visitProperties(c))
+		}
+	}
+}
+
+// Software systems and containers may have their own documentation and decisions.
+func (s *SemanticAnalyser) visitElementDocumentation(node *DirectiveNode, e *Element) {
+	if e.Type != "softwareSystem" && e.Type != "container" {
+		s.addWarning(node.Directive()+" is only allowed in workspaces, software systems and containers", node)
+		return
+	}
+	if node.Directive() == "!docs" {
+		e.Docs = s.visitDocs(node)
+	} else {
+		e.Adrs = s.visitAdrs(node)
+	}
+}
+
+// URLs must be absolute, e.g. https://example.com/docs.
+func (s *SemanticAnalyser) visitURL(node *ASTNode) string {
+	if len(node.Attributes) == 0 {
+		s.addWarning("Expected a URL", node)
+		return ""
+	}
+	value := node.Attributes[0]
+	if u, err := url.ParseRequestURI(value.Content); err != nil || u.Scheme == "" || u.Host == "" {
+		s.addErrorRange("Invalid URL "+value.Content, tokenRange(value))
+		return ""
+	}
+	return value.Content
+}
+
+// Perspectives have a name, a description and an optional value.
+func (s *SemanticAnalyser) visitPerspectives(node *ASTNode) []Perspective {
+	perspectives := make([]Perspective, 0)
+	for _, c := range node.Children {
+		if c.Kind != NodeProperty {
+			continue
+		}
+		if len(c.Attributes) == 0 {
+			s.addWarning("Expected a description for perspective "+c.Token.Content, c)
+			continue
+		}
+		p := Perspective{Name: c.Token.Content, Description: c.Attributes[0].Content}
+		if len(c.Attributes) > 1 {
+			p.Value = c.Attributes[1].Content
+		}
+		perspectives = append(perspectives, p)
+	}
+	return perspectives
+}
+
+func (s *SemanticAnalyser) visitElement(node *ElementNode, parent *Element) {
+	e := &Element{
+		Type:       node.ElementType(),
+		Name:       content(node.Name()),
+		Metadata:   content(node.Metadata()),
+		Parent:     parent,
+		Definition: tokenRange(&node.Token),
+	}
+	// elements of an archetype inherit its type and details
+	inherited := Details{}.clone()
+	if elementRoles[node.ElementType()] == nil {
+		archetype := s.resolveArchetype(&node.Token, node.ElementType())
+		if archetype == nil {
+			return
+		}
+		e.Type, e.Metadata = archetype.Type, cmp.Or(e.Metadata, archetype.Metadata)
+		inherited = archetype.Details.clone()
+	}
+	e.Details = inherited
+	e.Tags = slices.Clone(defaultTags[e.Type])
+	e.addTags(inherited.Tags...)
+	e.addTags(splitTags(content(node.Tags()))...)
+	e.Description = cmp.Or(content(node.Description()), e.Description)
+	e.Technology = cmp.Or(content(node.Technology()), e.Technology)
+	s.visitDetails(node.ASTNode, &e.Details)
+	if identifier := node.Identifier(); identifier != nil {
+		e.Identifier = s.qualify(identifier.Content, parent)
+		e.Definition = tokenRange(&identifier.Token)
+		if s.ws.Model.Element(e.Identifier) != nil {
+			s.addError("Duplicate identifier "+identifier.Content, identifier.Location)
+		}
+		s.addReference(&Reference{Range: e.Definition, Identifier: identifier.Content, Element: e, Definition: true})
+	}
+	s.ws.Model.addElement(e)
+	for _, c := range node.Children {
+		if is(c, NodeDirective, "!docs") || is(c, NodeDirective, "!adrs") {
+			s.visitElementDocumentation(&DirectiveNode{c}, e)
+		}
+	}
+	// instances refer to the deployed software system or container
+	if target := node.Instance(); target != nil {
+		s.resolveElement(target, parent)
+	}
+	s.visitElements(node.ASTNode, e)
+}
+
+// Resolves the source and the destination of a relationship, the source defaults to the enclosing element.
+func (s *SemanticAnalyser) visitRelationship(p *pendingRelationship) {
+	r := &Relationship{Details: Details{Tags: []string{"Relationship"}, Properties: make(map[string]string)}, Definition: tokenRange(&p.node.Token)}
+	// relationships of an archetype like --https-> inherit its details
+	if name := p.node.Archetype(); name != "" {
+		archetype := s.resolveArchetype(&p.node.Token, name)
+		if archetype == nil {
+			return
+		}
+		r.Details = archetype.Details.clone()
+		r.Tags = []string{"Relationship"}
+		r.addTags(archetype.Tags...)
+	}
+	r.addTags(splitTags(content(p.node.Tags()))...)
+	r.Description = cmp.Or(content(p.node.Description()), r.Description)
+	r.Technology = cmp.Or(content(p.node.Technology()), r.Technology)
+	s.visitDetails(p.node.ASTNode, &r.Details)
+	r.Source = p.scope
+	if source := p.node.Source(); source != nil {
+		r.Source = s.resolveElement(source, p.scope)
+	} else if p.scope == nil {
+		s.addWarning("Expected a source for the relationship", p.node)
+	}
+	if destination := p.node.Destination(); destination != nil {
+		r.Destination = s.resolveElement(destination, p.scope)
+	} else {
+		s.addWarning("Expected a destination for the relationship", p.node)
+	}
+	if r.Source == nil || r.Destination == nil {
+		return
+	}
+	if identifier := p.node.Identifier(); identifier != nil {
+		r.Identifier = identifier.Content
+		r.Definition = tokenRange(&identifier.Token)
+		if s.ws.Model.Relationship(r.Identifier) != nil {
+			s.addError("Duplicate identifier "+r.Identifier, identifier.Location)
+		}
+		s.addReference(&Reference{Range: r.Definition, Identifier: r.Identifier, Relationship: r, Definition: true})
+	}
+	s.ws.Model.addRelationship(r)
+	s.ws.Model.addImpliedRelationships(r, p.implied)
+}
+
+// Hierarchical identifiers are prefixed with the identifier of the enclosing element.
+func (s *SemanticAnalyser) qualify(identifier string, parent *Element) string {
+	if !s.hierarchical || parent == nil || parent.Identifier == "" {
+		return identifier
+	}
+	return parent.Identifier + "." + identifier
+}
+
+// Resolves an identifier used within the scope of an element, unknown identifiers are reported.
+func (s *SemanticAnalyser) resolveElement(token *Token, scope *Element) *Element {
+	e := s.lookup(token.Content, scope)
+	if e == nil {
+		d := s.addError("Unknown identifier "+token.Content, token.Location)
+		d.Code, d.Data = CodeUnknownIdentifier, []string{token.Content}
+		return nil
+	}
+	s.addReference(&Reference{Range: tokenRange(token), Identifier: token.Content, Element: e})
+	return e
+}
+
+// Hierarchical identifiers may be relative to the enclosing elements.
+func (s *SemanticAnalyser) lookup(identifier string, scope *Element) *Element {
+	if identifier == "this" {
+		return scope
+	}
+	if s.hierarchical {
+		for p := scope; p != nil; p = p.Parent {
+			if e := s.ws.Model.Element(p.Identifier + "." + identifier); p.Identifier != "" && e != nil {
+				return e
+			}
+		}
+	}
+	return s.ws.Model.Element(identifier)
+}
+
+func (s *SemanticAnalyser) addReference(r *Reference) {
+	s.ws.Model.Usages = append(s.ws.Model.Usages, r)
+}
+
+// Returns the content of the token, empty if missing.
+func content(t *Token) string {
+	if t == nil {
+		return ""
+	}
+	return t.Content
+}
+
+// Returns the content of the attribute with the given role, empty if missing.
+func attributeContent(node *ASTNode, role TokenRole) string {
+	if a := node.Attribute(role); a != nil {
+		return a.Content
+	}
+	return ""
 }
 
 func (s *SemanticAnalyser) visitGroup(node *ASTNode) *Group {
-	AugmentAttributes(node)
 	logger.Println("visitGroup")
-	return &Group{Name: node.Attributes[0].Content}
+	return &Group{Name: s.visitName(node)}
 }
 
 func (s *SemanticAnalyser) visitSoftwareSystem(node *ASTNode) *SoftwareSystem {
-	AugmentAttributes(node)
 	logger.Println("visitSoftwareSystem")
-	return &SoftwareSystem{Name: node.Attributes[0].Content}
+	return &SoftwareSystem{Name: s.visitName(node)}
 }
 
 func (s *SemanticAnalyser) visitDeploymentEnvironment(node *ASTNode) *DeploymentEnvironment {
-	AugmentAttributes(node)
 	logger.Println("visitDeploymentEnvironment")
-	return &DeploymentEnvironment{Name: node.Attributes[0].Content}
+	return &DeploymentEnvironment{Name: s.visitName(node)}
 }
 
 func isAssignment(node *ASTNode, t string) bool {
-	return node.Type == "assignment" && node.Children[1].Content == t
+	return node.Kind == NodeAssignment && len(node.Children) > 1 && is(node.Children[1], NodeElement, t)
 }
 
 // Visits a person node
 func (s *SemanticAnalyser) visitPerson(node *ASTNode) *Person {
-	AugmentAttributes(node)
 	logger.Println("visitPerson")
-	return &Person{Name: node.Attributes[0].Content}
+	return &Person{Name: s.visitName(node)}
 }
 
 // Visits a person node
 func (s *SemanticAnalyser) visitConfiguration(node *ASTNode) *Configuration {
 	config := &Configuration{}
 	for _, c := range node.Children {
-		if isKeyWordWithName(c, "scope") {
+		if is(c, NodeStatement, "scope") {
 			config.Scope = s.visitOptionWithPossibleValues(c, "landscape", "softwaresystem", "none")
-		} else if isKeyWordWithName(c, "visibility") {
+		} else if is(c, NodeStatement, "visibility") {
 			config.Visibility = s.visitOptionWithPossibleValues(c, "private", "public")
-		} else if isKeyWordWithName(c, "users") {
+		} else if is(c, NodeProperties, "users") {
 			config.Users = s.visitUsers(c)
-		} else if isKeyWordWithName(c, "properties") {
+		} else if is(c, NodeProperties, "properties") {
 			config.Properties = s.visitProperties(c)
 		} else if isBraces(c) {
 			continue
@@ -268,7 +975,7 @@ func getIdentifier(c *ASTNode) string {
 func (s *SemanticAnalyser) visitUsers(node *ASTNode) map[string]string {
 	props := make(map[string]string)
 	for _, c := range node.Children {
-		if c.Token.Type == TokenKeyword {
+		if c.Kind == NodeProperty && c.Token.Type == TokenKeyword {
 			props[c.Token.Content] = s.visitOptionWithPossibleValues(c, "write", "read")
 		}
 	}
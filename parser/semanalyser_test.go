@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -58,6 +60,8 @@ func TestSemanticAnalyser(t *testing.T) {
 			_, _, diags := sut.Analyse()
 			assert.Equal(t, 1, len(diags))
 			assert.Equal(t, "Invalid option, possible values [flat hierarchical]", diags[0].Message)
+			assert.Equal(t, CodeInvalidOption, diags[0].Code)
+			assert.Equal(t, []string{"flat", "hierarchical"}, diags[0].Data)
 		})
 		t.Run("properties allowed", func(t *testing.T) {
 			sut := NewTestAnalyser("workspace {\nproperties {\n\"key\" \"value\"\n}\nmodel {\n}\nviews {\n}\n}")
@@ -100,9 +104,11 @@ func TestSemanticAnalyser(t *testing.T) {
 		t.Run("augments attributes", func(t *testing.T) {
 			sut := NewTestAnalyser("workspace \"name\" \"description\" {\nmodel {\n}\nviews {\n}\n}")
 			_, ast, _ := sut.Analyse()
-			workspace := ast.Children[0]
-			assert.Equal(t, TokenName, workspace.Attributes[0].Type)
-			assert.Equal(t, TokenDescription, workspace.Attributes[1].Type)
+			workspace, ok := ast.Children[0].Typed().(*WorkspaceNode)
+			if assert.True(t, ok) {
+				assert.Equal(t, "name", workspace.Name().Content)
+				assert.Equal(t, "description", workspace.Description().Content)
+			}
 		})
 	})
 	t.Run("configuration", func(t *testing.T) {
@@ -246,6 +252,478 @@ func TestSemanticAnalyser(t *testing.T) {
 			assert.Equal(t, &DeploymentEnvironment{Name: "name"}, ws.Model.DeploymentEnvironments["name"])
 		})
 	})
+	t.Run("elements", func(t *testing.T) {
+		t.Run("are registered by their identifier", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\nss = softwareSystem \"System\" \"Description\" \"Internal\" {\napi = container \"API\" \"\" \"Go\"\n}\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			ss := ws.Model.Element("ss")
+			if assert.NotNil(t, ss) {
+				assert.Equal(t, "System", ss.Name)
+				assert.Equal(t, []string{"Element", "Software System", "Internal"}, ss.Tags)
+			}
+			api := ws.Model.Element("API")
+			if assert.NotNil(t, api) {
+				assert.Equal(t, "Go", api.Technology)
+				assert.Equal(t, ss, api.Parent)
+				assert.Equal(t, Range{Start: Location{Source: "test.dsl", Line: 3, Pos: 0}, End: Location{Source: "test.dsl", Line: 3, Pos: 3}}, api.Definition)
+			}
+		})
+		t.Run("groups are not part of the hierarchy", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\n!identifiers hierarchical\nmodel {\nss = softwareSystem \"System\" {\ngroup \"Group\" {\napi = container \"API\"\n}\n}\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			assert.Nil(t, ws.Model.Element("api"))
+			if assert.NotNil(t, ws.Model.Element("ss.api")) {
+				assert.Equal(t, "ss", ws.Model.Element("ss.api").Parent.Identifier)
+			}
+		})
+		t.Run("duplicate identifiers are reported", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\nuser = person \"A\"\nuser = person \"B\"\n}\nviews {\n}\n}")
+			_, _, diags := sut.Analyse()
+			if assert.Equal(t, 1, len(diags)) {
+				assert.Equal(t, "Duplicate identifier user", diags[0].Message)
+				assert.Equal(t, 3, diags[0].Location.Line)
+			}
+		})
+	})
+	t.Run("relationships", func(t *testing.T) {
+		t.Run("connect the referred elements", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\nuser = person \"User\"\nrel = user -> ss \"Uses\" \"HTTPS\"\nss = softwareSystem \"System\"\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			rel := ws.Model.Relationship("rel")
+			if assert.NotNil(t, rel) {
+				assert.Equal(t, ws.Model.Element("user"), rel.Source)
+				assert.Equal(t, ws.Model.Element("ss"), rel.Destination)
+				assert.Equal(t, "Uses", rel.Description)
+				assert.Equal(t, "HTTPS", rel.Technology)
+			}
+		})
+		t.Run("source defaults to the enclosing element", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\nss = softwareSystem \"System\"\nuser = person \"User\" {\n-> ss\nthis -> ss\n}\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			if assert.Equal(t, 2, len(ws.Model.Relationships)) {
+				assert.Equal(t, ws.Model.Element("user"), ws.Model.Relationships[0].Source)
+				assert.Equal(t, ws.Model.Element("user"), ws.Model.Relationships[1].Source)
+			}
+		})
+		t.Run("hierarchical identifiers resolve relative to the enclosing elements", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\n!identifiers hierarchical\nmodel {\nss = softwareSystem \"System\" {\napi = container \"API\"\ndb = container \"DB\" {\n-> api\n}\n}\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			if assert.Equal(t, 1, len(ws.Model.Relationships)) {
+				assert.Equal(t, ws.Model.Element("ss.api"), ws.Model.Relationships[0].Destination)
+			}
+		})
+		t.Run("unknown identifiers are reported", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\nuser = person \"User\"\nuser -> missing\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, ws.Model.Relationships)
+			if assert.Equal(t, 1, len(diags)) {
+				assert.Equal(t, "Unknown identifier missing", diags[0].Message)
+				assert.Equal(t, Location{Source: "test.dsl", Line: 3, Pos: 8}, diags[0].Location)
+				assert.Equal(t, CodeUnknownIdentifier, diags[0].Code)
+				assert.Equal(t, []string{"missing"}, diags[0].Data)
+			}
+		})
+	})
+	t.Run("references", func(t *testing.T) {
+		sut := NewTestAnalyser("workspace {\nmodel {\nuser = person \"User\"\nss = softwareSystem \"System\"\nuser -> ss\n}\nviews {\nsystemContext ss {\ninclude user\n}\n}\n}")
+		ws, _, diags := sut.Analyse()
+		assert.Empty(t, diags)
+		t.Run("definitions are references", func(t *testing.T) {
+			ref := ws.Model.ReferenceAt(Location{Source: "test.dsl", Line: 2, Pos: 1})
+			if assert.NotNil(t, ref) {
+				assert.True(t, ref.Definition)
+				assert.Equal(t, ws.Model.Element("user"), ref.Element)
+			}
+		})
+		t.Run("the end of an identifier belongs to the reference", func(t *testing.T) {
+			ref := ws.Model.ReferenceAt(Location{Source: "test.dsl", Line: 4, Pos: 10})
+			if assert.NotNil(t, ref) {
+				assert.False(t, ref.Definition)
+				assert.Equal(t, ws.Model.Element("ss"), ref.Element)
+			}
+		})
+		t.Run("views refer to elements", func(t *testing.T) {
+			assert.Equal(t, ws.Model.Element("ss"), ws.Model.ReferenceAt(Location{Source: "test.dsl", Line: 7, Pos: 15}).Element)
+			assert.Equal(t, ws.Model.Element("user"), ws.Model.ReferenceAt(Location{Source: "test.dsl", Line: 8, Pos: 9}).Element)
+		})
+		t.Run("keywords are not references", func(t *testing.T) {
+			assert.Nil(t, ws.Model.ReferenceAt(Location{Source: "test.dsl", Line: 3, Pos: 7}))
+		})
+		t.Run("identifiers of expressions are references", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\nuser = person \"User\"\nss = softwareSystem \"System\"\nuser -> ss\n}\nviews {\nsystemLandscape {\ninclude ->ss->\nexclude \"user -> ss\"\n}\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			ref := ws.Model.ReferenceAt(Location{Source: "test.dsl", Line: 8, Pos: 10})
+			if assert.NotNil(t, ref) {
+				assert.Equal(t, ws.Model.Element("ss"), ref.Element)
+				assert.Equal(t, Range{Start: Location{Source: "test.dsl", Line: 8, Pos: 10}, End: Location{Source: "test.dsl", Line: 8, Pos: 12}}, ref.Range)
+			}
+			ref = ws.Model.ReferenceAt(Location{Source: "test.dsl", Line: 9, Pos: 9})
+			if assert.NotNil(t, ref) {
+				assert.Equal(t, ws.Model.Element("user"), ref.Element)
+				assert.Equal(t, Range{Start: Location{Source: "test.dsl", Line: 9, Pos: 9}, End: Location{Source: "test.dsl", Line: 9, Pos: 13}}, ref.Range)
+			}
+		})
+	})
+	t.Run("directives reopening elements", func(t *testing.T) {
+		t.Run("!element amends the target", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\nss = softwareSystem \"System\"\n!element ss {\ntags \"Internal,Legacy\"\ndescription \"Amended\"\nproperties {\n\"owner\" \"team\"\n}\napi = container \"API\"\n-> ss\n}\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			ss := ws.Model.Element("ss")
+			assert.Equal(t, []string{"Element", "Software System", "Internal", "Legacy"}, ss.Tags)
+			assert.Equal(t, "Amended", ss.Description)
+			assert.Equal(t, map[string]string{"owner": "team"}, ss.Properties)
+			if assert.NotNil(t, ws.Model.Element("api")) {
+				assert.Equal(t, ss, ws.Model.Element("api").Parent)
+			}
+			if assert.Equal(t, 1, len(ws.Model.Relationships)) {
+				assert.Equal(t, ss, ws.Model.Relationships[0].Source)
+			}
+		})
+		t.Run("!ref and !extend are synonyms", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\nss = softwareSystem \"System\"\n!ref ss {\ntags \"A\"\n}\n!extend ss {\ntags \"B\"\n}\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			assert.Equal(t, []string{"Element", "Software System", "A", "B"}, ws.Model.Element("ss").Tags)
+		})
+		t.Run("targets may be canonical names", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\nsoftwareSystem \"System\" {\ncontainer \"API\"\n}\napi = !element \"Container://System.API\" {\ntags \"Public\"\n}\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			api := ws.Model.Element("api")
+			if assert.NotNil(t, api) {
+				assert.Equal(t, "API", api.Name)
+				assert.Contains(t, api.Tags, "Public")
+			}
+		})
+		t.Run("hierarchical identifiers of new children are qualified with the target", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\n!identifiers hierarchical\nmodel {\nss = softwareSystem \"System\"\n!element ss {\napi = container \"API\"\n}\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			assert.NotNil(t, ws.Model.Element("ss.api"))
+		})
+		t.Run("inherited elements can be reopened", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace extends base.dsl {\nmodel {\n!element user {\ntags \"Admin\"\n}\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			assert.Contains(t, ws.Model.Element("user").Tags, "Admin")
+		})
+		t.Run("!relationship amends the target", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\n!relationship rel {\ntags \"Async\"\ntechnology \"Kafka\"\n}\nuser = person \"User\"\nrel = user -> user\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			rel := ws.Model.Relationship("rel")
+			assert.Equal(t, []string{"Relationship", "Async"}, rel.Tags)
+			assert.Equal(t, "Kafka", rel.Technology)
+		})
+		t.Run("unresolved targets are reported", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\n!element missing {\n}\n!element \"SoftwareSystem://Missing\" {\n}\n!relationship rel {\n}\n!element\n}\nviews {\n}\n}")
+			_, _, diags := sut.Analyse()
+			if assert.Equal(t, 4, len(diags)) {
+				assert.Equal(t, "Unknown identifier missing", diags[0].Message)
+				assert.Equal(t, "Unknown element SoftwareSystem://Missing", diags[1].Message)
+				assert.Equal(t, "Expected an element for !element", diags[2].Message)
+				assert.Equal(t, "Unknown relationship rel", diags[3].Message)
+				assert.Equal(t, Location{Source: "test.dsl", Line: 6, Pos: 14}, diags[3].Location)
+			}
+		})
+	})
+	t.Run("bulk edits", func(t *testing.T) {
+		t.Run("!elements amends every selected element", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\nss = softwareSystem \"System\" {\ndb = container \"DB\" \"\" \"\" \"Database\"\napi = container \"API\"\n}\n!elements \"element.tag==Database\" {\ntags \"Persistence\"\nproperties {\n\"backup\" \"daily\"\n}\n}\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			assert.Contains(t, ws.Model.Element("db").Tags, "Persistence")
+			assert.Equal(t, "daily", ws.Model.Element("db").Properties["backup"])
+			assert.NotContains(t, ws.Model.Element("api").Tags, "Persistence")
+		})
+		t.Run("!relationships amends every selected relationship", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\na = person \"A\"\nb = person \"B\"\na -> b\n!relationships \"*->*\" {\ntags \"Checked\"\n}\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			assert.Equal(t, []string{"Relationship", "Checked"}, ws.Model.Relationships[0].Tags)
+		})
+		t.Run("expressions of the wrong kind are reported", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\n!elements \"*->*\" {\n}\n!relationships \"*\" {\n}\n}\nviews {\n}\n}")
+			_, _, diags := sut.Analyse()
+			if assert.Equal(t, 2, len(diags)) {
+				assert.Equal(t, "Expected an element expression", diags[0].Message)
+				assert.Equal(t, "Expected a relationship expression", diags[1].Message)
+			}
+		})
+		t.Run("invalid expressions are reported at the failing part", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\n!elements \"element.tag==A || missing\" {\n}\n}\nviews {\n}\n}")
+			_, _, diags := sut.Analyse()
+			if assert.Equal(t, 1, len(diags)) {
+				assert.Equal(t, "Unknown identifier missing", diags[0].Message)
+				assert.Equal(t, Location{Source: "test.dsl", Line: 2, Pos: 29}, diags[0].Location)
+				assert.Equal(t, Location{Source: "test.dsl", Line: 2, Pos: 36}, diags[0].End)
+				assert.Equal(t, []string{"missing"}, diags[0].Data)
+			}
+		})
+	})
+	t.Run("documentation", func(t *testing.T) {
+		dir := t.TempDir()
+		_ = os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+		_ = os.MkdirAll(filepath.Join(dir, "adrs"), 0755)
+		_ = os.WriteFile(filepath.Join(dir, "docs", "01-context.md"), []byte("# Context"), 0644)
+		_ = os.WriteFile(filepath.Join(dir, "adrs", "0001-record-decisions.md"), []byte("# 1. Record decisions\n\nDate: 2024-01-01\n\n## Status\n\nAccepted\n"), 0644)
+		_ = os.MkdirAll(filepath.Join(dir, "docs dir"), 0755)
+		_ = os.WriteFile(filepath.Join(dir, "docs dir", "01-context.md"), []byte("# Context"), 0644)
+		_ = os.WriteFile(filepath.Join(dir, "adrs", "0002-broken.md"), []byte("Nothing to see\n"), 0644)
+		analyse := func(content string) (*Workspace, []*Diagnostic) {
+			sut := NewAnalyser(filepath.Join(dir, "workspace.dsl"), content, &FSIncluder{})
+			ws, _, diags := sut.Analyse()
+			return ws, diags
+		}
+		t.Run("missing paths are reported", func(t *testing.T) {
+			_, diags := analyse("workspace {\n!docs missing\nmodel {\n}\nviews {\n}\n}")
+			if assert.Equal(t, 1, len(diags)) {
+				assert.Equal(t, "Path missing does not exist", diags[0].Message)
+				assert.Equal(t, Location{Source: filepath.Join(dir, "workspace.dsl"), Line: 1, Pos: 6}, diags[0].Location)
+			}
+		})
+		t.Run("quoted paths may contain spaces", func(t *testing.T) {
+			ws, diags := analyse("workspace {\n!docs \"docs dir\" \"com.example.Importer\"\nmodel {\n}\nviews {\n}\n}")
+			assert.Empty(t, diags)
+			assert.Equal(t, "docs dir", ws.Docs.Path)
+			assert.Equal(t, "com.example.Importer", ws.Docs.Fqcn)
+		})
+		t.Run("documentation is read with the includer", func(t *testing.T) {
+			sut := NewAnalyser("/ws/workspace.dsl", "workspace {\n!adrs some/path\nmodel {\n}\nviews {\n}\n}", &FakeIncluder{})
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			if assert.Equal(t, 1, len(ws.Adrs.Decisions)) {
+				assert.Equal(t, "Record architecture decisions", ws.Adrs.Decisions[0].Title)
+			}
+			assert.True(t, sut.IncludeGraph().DependsOn("/ws/some/path/0002-new.md"))
+			assert.Equal(t, []string{"/ws/some/path"}, sut.IncludeGraph().Documentation())
+		})
+		t.Run("unknown importers are reported", func(t *testing.T) {
+			_, diags := analyse("workspace {\n!adrs adrs nygard\nmodel {\n}\nviews {\n}\n}")
+			if assert.Equal(t, 1, len(diags)) {
+				assert.Equal(t, "Unknown importer nygard, expected adrtools, madr, log4brains or a fully qualified class name", diags[0].Message)
+			}
+		})
+		t.Run("decisions are read and malformed ones reported on the directive", func(t *testing.T) {
+			ws, diags := analyse("workspace {\n!adrs adrs adrtools\nmodel {\n}\nviews {\n}\n}")
+			if assert.Equal(t, 2, len(diags)) {
+				assert.Equal(t, "ADR 0002-broken.md has no title", diags[0].Message)
+				assert.Equal(t, "ADR 0002-broken.md has no status", diags[1].Message)
+				assert.Equal(t, 1, diags[0].Location.Line)
+			}
+			if assert.Equal(t, 2, len(ws.Adrs.Decisions)) {
+				assert.Equal(t, &Decision{ID: "1", Title: "Record decisions", Status: "Accepted", Date: "2024-01-01", Path: filepath.Join(dir, "adrs", "0001-record-decisions.md")}, ws.Adrs.Decisions[0])
+			}
+		})
+		t.Run("software systems and containers may have documentation", func(t *testing.T) {
+			ws, diags := analyse("workspace {\nmodel {\nss = softwareSystem \"System\" {\n!docs docs\napi = container \"API\" {\n!docs docs/01-context.md\n}\n}\nuser = person \"User\" {\n!docs docs\n}\n}\nviews {\n}\n}")
+			if assert.Equal(t, 1, len(diags)) {
+				assert.Equal(t, "!docs is only allowed in workspaces, software systems and containers", diags[0].Message)
+				assert.Equal(t, 9, diags[0].Location.Line)
+			}
+			assert.Equal(t, "docs", ws.Model.Element("ss").Docs.Path)
+			assert.Equal(t, "docs/01-context.md", ws.Model.Element("api").Docs.Path)
+		})
+	})
+	t.Run("details", func(t *testing.T) {
+		t.Run("blocks of elements and relationships amend their details", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\n!impliedRelationships false\nmodel {\na = person \"A\" {\ndescription \"Customer\"\ntags \"External\" \"Web\"\nurl \"https://example.com/a\"\nproperties {\n\"owner\" \"Sales\"\n}\nperspectives {\n\"Security\" \"Authenticated\" \"High\"\n}\n}\nb = person \"B\"\na -> b \"Calls\" {\ntechnology \"Phone\"\ntags \"Sync\"\nurl \"https://example.com/calls\"\n}\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			a := ws.Model.Element("a")
+			assert.Equal(t, "Customer", a.Description)
+			assert.Equal(t, []string{"Element", "Person", "External", "Web"}, a.Tags)
+			assert.Equal(t, "https://example.com/a", a.URL)
+			assert.Equal(t, map[string]string{"owner": "Sales"}, a.Properties)
+			assert.Equal(t, []Perspective{{Name: "Security", Description: "Authenticated", Value: "High"}}, a.Perspectives)
+			if assert.Equal(t, 1, len(ws.Model.Relationships)) {
+				r := ws.Model.Relationships[0]
+				assert.Equal(t, "Phone", r.Technology)
+				assert.Equal(t, []string{"Relationship", "Sync"}, r.Tags)
+				assert.Equal(t, "https://example.com/calls", r.URL)
+			}
+		})
+		t.Run("invalid URLs are reported", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\na = person \"A\" {\nurl \"example.com\"\n}\n}\nviews {\n}\n}")
+			_, _, diags := sut.Analyse()
+			if assert.Equal(t, 1, len(diags)) {
+				assert.Equal(t, "Invalid URL example.com", diags[0].Message)
+				assert.Equal(t, Location{Source: "test.dsl", Line: 3, Pos: 4}, diags[0].Location)
+				assert.Equal(t, Location{Source: "test.dsl", Line: 3, Pos: 17}, diags[0].End)
+			}
+		})
+		t.Run("perspectives need a description", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\na = person \"A\" {\nperspectives {\n\"Security\"\n}\n}\n}\nviews {\n}\n}")
+			_, _, diags := sut.Analyse()
+			if assert.Equal(t, 1, len(diags)) {
+				assert.Equal(t, "Expected a description for perspective Security", diags[0].Message)
+			}
+		})
+	})
+	t.Run("archetypes", func(t *testing.T) {
+		t.Run("elements inherit the type and details of their archetype", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\narchetypes {\napplication = container {\ntechnology \"Java\"\ntags \"Application\"\n}\nmicroservice = application {\ntags \"Microservice\"\n}\n}\nss = softwareSystem \"System\" {\napi = microservice \"API\" \"Serves requests\" \"Kotlin\"\nweb = application \"Web\"\n}\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			api, web := ws.Model.Element("api"), ws.Model.Element("web")
+			assert.Equal(t, "container", api.Type)
+			assert.Equal(t, "Kotlin", api.Technology)
+			assert.Equal(t, "Serves requests", api.Description)
+			assert.Equal(t, []string{"Element", "Container", "Application", "Microservice"}, api.Tags)
+			assert.Equal(t, ws.Model.Element("ss"), api.Parent)
+			assert.Equal(t, "Java", web.Technology)
+			assert.Equal(t, "application", ws.Model.Archetype("microservice").Base.Name)
+		})
+		t.Run("custom elements keep the metadata of their archetype", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\narchetypes {\nhardware = element {\nmetadata \"Hardware\"\n}\n}\nrouter = hardware \"Router\"\nswitch = element \"Switch\" \"Network\"\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			assert.Equal(t, "element", ws.Model.Element("router").Type)
+			assert.Equal(t, "Hardware", ws.Model.Element("router").Metadata)
+			assert.Equal(t, "Network", ws.Model.Element("switch").Metadata)
+			assert.Equal(t, "CustomElement://Router", ws.Model.Element("router").CanonicalName())
+		})
+		t.Run("relationships inherit the details of their archetype", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\n!impliedRelationships false\nmodel {\narchetypes {\nhttps = -> {\ntechnology \"HTTPS\"\ntags \"Secure\"\n}\n}\na = person \"A\"\nb = person \"B\"\na --https-> b \"Calls\"\n}\nviews {\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			if assert.Equal(t, 1, len(ws.Model.Relationships)) {
+				r := ws.Model.Relationships[0]
+				assert.Equal(t, "Calls", r.Description)
+				assert.Equal(t, "HTTPS", r.Technology)
+				assert.Equal(t, []string{"Relationship", "Secure"}, r.Tags)
+			}
+		})
+		t.Run("usages of archetypes are references", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\narchetypes {\napplication = container\n}\nss = softwareSystem \"System\" {\napi = application \"API\"\n}\n}\nviews {\n}\n}")
+			ws, _, _ := sut.Analyse()
+			ref := ws.Model.ReferenceAt(Location{Source: "test.dsl", Line: 6, Pos: 8})
+			if assert.NotNil(t, ref) {
+				assert.Equal(t, ws.Model.Archetype("application"), ref.Archetype)
+			}
+		})
+		t.Run("unknown element types and archetypes are reported", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\narchetypes {\nsecure = --missing->\n}\napi = aplication \"API\"\n}\nviews {\n}\n}")
+			_, _, diags := sut.Analyse()
+			if assert.Equal(t, 2, len(diags)) {
+				assert.Equal(t, "Unknown archetype missing", diags[0].Message)
+				assert.Equal(t, "Unknown element type or archetype aplication", diags[1].Message)
+				assert.Equal(t, Location{Source: "test.dsl", Line: 5, Pos: 6}, diags[1].Location)
+			}
+		})
+	})
+	t.Run("views", func(t *testing.T) {
+		t.Run("invalid include expressions are reported", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\nss = softwareSystem \"System\"\n}\nviews {\nsystemContext ss {\ninclude ->missing->\nexclude \"element.type==Box\"\n}\n}\n}")
+			_, _, diags := sut.Analyse()
+			if assert.Equal(t, 2, len(diags)) {
+				assert.Equal(t, "Unknown identifier missing", diags[0].Message)
+				assert.Equal(t, Location{Source: "test.dsl", Line: 6, Pos: 10}, diags[0].Location)
+				assert.Equal(t, "Unknown element type Box", diags[1].Message)
+				assert.Equal(t, Location{Source: "test.dsl", Line: 7, Pos: 23}, diags[1].Location)
+			}
+		})
+		t.Run("steps of dynamic views need a relationship", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\nuser = person \"User\"\nss = softwareSystem \"System\" {\napi = container \"API\"\n}\nuser -> api\n}\nviews {\ndynamic * {\nuser -> ss\nss -> user\n}\n}\n}")
+			_, _, diags := sut.Analyse()
+			if assert.Equal(t, 1, len(diags)) {
+				assert.Equal(t, "A relationship between System and User does not exist in model", diags[0].Message)
+				assert.Equal(t, 11, diags[0].Location.Line)
+			}
+		})
+		t.Run("filtered views must be based on an existing view", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace {\nmodel {\n}\nviews {\nfiltered \"missing\" include \"Element\"\n}\n}")
+			_, _, diags := sut.Analyse()
+			if assert.Equal(t, 1, len(diags)) {
+				assert.Equal(t, "Unknown view missing", diags[0].Message)
+			}
+		})
+	})
+	t.Run("extends", func(t *testing.T) {
+		t.Run("elements of the base workspace are inherited", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace extends base.dsl {\nmodel {\nss = softwareSystem \"System\"\nuser -> ss\n}\n}")
+			ws, ast, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			assert.Equal(t, "base.dsl", ws.Extends)
+			assert.Equal(t, RoleBase, ast.Children[0].Attributes[1].Role)
+			user := ws.Model.Element("user")
+			if assert.NotNil(t, user) {
+				assert.Equal(t, "base.dsl", user.Definition.Start.Source)
+			}
+			assert.Equal(t, 1, len(ws.Model.Relationships))
+		})
+		t.Run("archetypes of the base workspace can be used", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace extends platform.dsl {\nmodel {\narchetypes {\nservice = application\n}\n!extend ss {\napi = application \"API\"\nworker = service \"Worker\"\n}\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			for _, identifier := range []string{"api", "worker"} {
+				if e := ws.Model.Element(identifier); assert.NotNil(t, e) {
+					assert.Equal(t, "container", e.Type)
+					assert.Equal(t, "ss", e.Parent.Identifier)
+				}
+			}
+		})
+		t.Run("views are added to the views of the base workspace", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace extends platform.dsl {\nviews {\nsystemLandscape \"Landscape\" {\ninclude *\n}\n}\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			keys := make([]string, 0)
+			for _, v := range ws.Views().Views {
+				keys = append(keys, v.Key)
+			}
+			assert.Equal(t, []string{"Context", "Landscape"}, keys)
+		})
+		t.Run("the model and views are optional", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace extends base.dsl {\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			assert.NotNil(t, ws.Model.Element("user"))
+		})
+		t.Run("the base workspace is part of the include graph", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace extends base.dsl {\n}")
+			_, _, _ = sut.Analyse()
+			assert.True(t, sut.IncludeGraph().DependsOn("base.dsl"))
+			assert.False(t, sut.IncludeGraph().Contains("base.dsl"))
+			assert.Equal(t, "base.dsl", sut.IncludeGraph().Base().Root)
+		})
+		t.Run("bases of the base are inherited", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace extends derived.dsl {\n}")
+			ws, _, diags := sut.Analyse()
+			assert.Empty(t, diags)
+			assert.NotNil(t, ws.Model.Element("user"))
+			assert.True(t, sut.IncludeGraph().DependsOn("base.dsl"))
+		})
+		t.Run("missing bases are reported", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace extends missing.dsl {\n}")
+			_, _, diags := sut.Analyse()
+			if assert.Equal(t, 1, len(diags)) {
+				assert.Equal(t, "Failed to extend missing.dsl: failed to open missing.dsl", diags[0].Message)
+			}
+		})
+		t.Run("workspaces cannot extend themselves", func(t *testing.T) {
+			sut := NewTestAnalyser("workspace extends test.dsl {\n}")
+			_, _, diags := sut.Analyse()
+			if assert.Equal(t, 1, len(diags)) {
+				assert.Equal(t, "Cyclic extension of test.dsl", diags[0].Message)
+			}
+		})
+	})
+	t.Run("incomplete elements are analysed while editing", func(t *testing.T) {
+		sut := NewTestAnalyser("workspace {\nmodel {\nperson\ngroup {\n}\nsomeone =\n}\nviews {\n}\n}")
+		ws, _, diags := sut.Analyse()
+		assert.NotNil(t, ws.Model)
+		if assert.Equal(t, 2, len(diags)) {
+			assert.Equal(t, "Expected a name for person", diags[0].Message)
+			assert.Equal(t, "Expected a name for group", diags[1].Message)
+		}
+	})
 	t.Run("augments properties", func(t *testing.T) {
 		sut := NewTestAnalyser("workspace \"name\" \"description\" {\nmodel {\n}\nviews {\nproperties {\n\"key\" \"value\"\n}\n}\n}")
 		_, ast, _ := sut.Analyse()
@@ -253,8 +731,8 @@ func TestSemanticAnalyser(t *testing.T) {
 		views := ws.Children[2]
 		properties := views.Children[1]
 		property := properties.Children[1]
-		assert.Equal(t, TokenName, property.Token.Type)
-		assert.Equal(t, TokenValue, property.Attributes[0].Type)
+		assert.Equal(t, RoleName, property.Token.Role)
+		assert.Equal(t, RoleValue, property.Attributes[0].Role)
 	})
 	t.Run("augments person attributes", func(t *testing.T) {
 		sut := NewTestAnalyser("workspace {\nmodel {\nperson \"name\" \"description\" \"tags\" \n}\nviews {\n}\n}")
@@ -262,14 +740,14 @@ func TestSemanticAnalyser(t *testing.T) {
 		ws := ast.Children[0]
 		model := ws.Children[1]
 		person := model.Children[1]
-		assert.Equal(t, TokenName, person.Attributes[0].Type)
-		assert.Equal(t, TokenDescription, person.Attributes[1].Type)
-		assert.Equal(t, TokenTags, person.Attributes[2].Type)
+		assert.Equal(t, RoleName, person.Attributes[0].Role)
+		assert.Equal(t, RoleDescription, person.Attributes[1].Role)
+		assert.Equal(t, RoleTags, person.Attributes[2].Role)
 	})
 }
 
 func NewTestAnalyser(content string) *SemanticAnalyser {
-	return &SemanticAnalyser{parser: New("test.dsl", content, &FakeIncluder{})}
+	return NewAnalyser("test.dsl", content, &FakeIncluder{})
 }
 
 func firstGroup(haystack map[string]*Group) *Group {
@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+)
+
+// View is a view of the workspace, its content is selected by the include and exclude statements.
+type View struct {
+	Type string
+	Key  string
+	// Software system, container or deployment scope of the view, nil when the view is not scoped
+	Scope       *Element
+	Environment string
+	// Range of the whole view including its block
+	Range      Range
+	statements []*viewStatement
+	steps      []*viewStep
+	// filtered views show the content of their base view with or without the given tags
+	base        *View
+	tags        []string
+	excludeTags bool
+}
+
+type viewStatement struct {
+	include bool
+	// the wildcard selects the default content of the view instead of every element
+	wildcard   bool
+	expression *Expression
+}
+
+// A step of a dynamic view.
+type viewStep struct {
+	source      *Element
+	destination *Element
+}
+
+// Element types a wildcard includes into a view besides the elements within the scope.
+var wildcardTypes = map[string][]string{
+	"systemContext": {"person", "softwareSystem"},
+	"container":     {"person", "softwareSystem"},
+	"component":     {"person", "softwareSystem", "container"},
+}
+
+// Returns the view with the given key.
+func (vs *ViewSet) View(key string) *View {
+	for _, v := range vs.Views {
+		if v.Key != "" && v.Key == key {
+			return v
+		}
+	}
+	return nil
+}
+
+// Returns the view whose definition contains the location.
+func (vs *ViewSet) ViewAt(loc Location) *View {
+	for _, v := range vs.Views {
+		if loc.Source == v.Range.Start.Source && !loc.Before(v.Range.Start) && !v.Range.End.Before(loc) {
+			return v
+		}
+	}
+	return nil
+}
+
+// Returns the elements and relationships shown by the view of the model. Statements are applied in order, the
+// relationships between the included elements are shown unless excluded.
+func (v *View) Contents(m *Model) ([]*Element, []*Relationship) {
+	if v.base != nil {
+		return v.filter(m)
+	}
+	elements := make([]*Element, 0)
+	for _, step := range v.steps {
+		elements = appendMissing(elements, step.source, step.destination)
+	}
+	excluded := make([]*Relationship, 0)
+	for _, st := range v.statements {
+		var selected []*Element
+		var relationships []*Relationship
+		if st.wildcard {
+			selected = v.wildcard(m)
+		} else if st.expression.SelectsElements() {
+			selected = st.expression.Elements(m)
+		} else {
+			relationships = st.expression.Relationships(m)
+		}
+		if st.include {
+			elements = appendMissing(elements, selected...)
+			for _, r := range relationships {
+				elements = appendMissing(elements, r.Source, r.Destination)
+				excluded = slices.DeleteFunc(excluded, func(e *Relationship) bool { return e == r })
+			}
+			continue
+		}
+		elements = slices.DeleteFunc(elements, func(e *Element) bool { return slices.Contains(selected, e) })
+		excluded = append(excluded, relationships...)
+	}
+	relationships := make([]*Relationship, 0)
+	for _, r := range m.Relationships {
+		if slices.Contains(elements, r.Source) && slices.Contains(elements, r.Destination) && !slices.Contains(excluded, r) {
+			relationships = append(relationships, r)
+		}
+	}
+	return elements, relationships
+}
+
+// Returns the default content of the view, e.g. the containers of the software system with the people and
+// software systems using them.
+func (v *View) wildcard(m *Model) []*Element {
+	elements := make([]*Element, 0)
+	switch v.Type {
+	case "systemLandscape":
+		for _, e := range m.Elements {
+			if e.Type == "person" || e.Type == "softwareSystem" {
+				elements = append(elements, e)
+			}
+		}
+	case "custom":
+		for _, e := range m.Elements {
+			if e.Type == "element" {
+				elements = append(elements, e)
+			}
+		}
+	case "deployment":
+		for _, e := range m.Elements {
+			if env := v.environmentOf(e); env != nil && env != e {
+				elements = append(elements, e)
+			}
+		}
+	case "systemContext", "container", "component":
+		if v.Scope == nil {
+			return elements
+		}
+		inner := make([]*Element, 0)
+		if v.Type == "systemContext" {
+			inner = append(inner, v.Scope)
+		} else {
+			for _, e := range m.Elements {
+				if e.Parent == v.Scope {
+					inner = append(inner, e)
+				}
+			}
+		}
+		elements = append(elements, inner...)
+		for _, r := range m.Relationships {
+			for _, pair := range [][2]*Element{{r.Source, r.Destination}, {r.Destination, r.Source}} {
+				if slices.Contains(inner, pair[0]) && pair[1] != v.Scope && slices.Contains(wildcardTypes[v.Type], pair[1].Type) {
+					elements = appendMissing(elements, pair[1])
+				}
+			}
+		}
+	}
+	return elements
+}
+
+// Returns the deployment environment of the view containing the element.
+func (v *View) environmentOf(e *Element) *Element {
+	for p := e; p != nil; p = p.Parent {
+		if p.isEnvironment() && strings.EqualFold(p.Name, v.Environment) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Returns the content of the base view with or without the elements and relationships having every tag.
+func (v *View) filter(m *Model) ([]*Element, []*Relationship) {
+	elements, relationships := v.base.Contents(m)
+	elements = slices.DeleteFunc(elements, func(e *Element) bool { return hasTags(e.Tags, v.tags) == v.excludeTags })
+	relationships = slices.DeleteFunc(relationships, func(r *Relationship) bool {
+		return hasTags(r.Tags, v.tags) == v.excludeTags || !slices.Contains(elements, r.Source) || !slices.Contains(elements, r.Destination)
+	})
+	return elements, relationships
+}
+
+func appendMissing(elements []*Element, added ...*Element) []*Element {
+	for _, e := range added {
+		if e != nil && !slices.Contains(elements, e) {
+			elements = append(elements, e)
+		}
+	}
+	return elements
+}
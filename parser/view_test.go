@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestView(t *testing.T) {
+	model := "workspace {\nmodel {\nuser = person \"User\"\nmail = softwareSystem \"Mail\"\nss = softwareSystem \"System\" {\napi = container \"API\" \"\" \"\" \"Public\"\ndb = container \"DB\"\n}\nuser -> api \"Uses\"\napi -> db \"Reads\" \"\" \"Async\"\napi -> mail \"Sends\"\n}\n"
+	contents := func(t *testing.T, views string, key string) ([]string, []string) {
+		sut := NewTestAnalyser(model + "views {\n" + views + "\n}\n}")
+		ws, _, diags := sut.Analyse()
+		assert.Empty(t, diags)
+		view := ws.Views().View(key)
+		if !assert.NotNil(t, view) {
+			return nil, nil
+		}
+		elements, relationships := view.Contents(ws.Model)
+		names, descriptions := make([]string, 0), make([]string, 0)
+		for _, e := range elements {
+			names = append(names, e.Name)
+		}
+		for _, r := range relationships {
+			descriptions = append(descriptions, r.Description)
+		}
+		return names, descriptions
+	}
+	t.Run("wildcard of a system landscape includes people and software systems", func(t *testing.T) {
+		elements, relationships := contents(t, "systemLandscape \"key\" {\ninclude *\n}", "key")
+		assert.Equal(t, []string{"User", "Mail", "System"}, elements)
+		// implied by the relationships of the containers
+		assert.Equal(t, []string{"Uses", "Sends"}, relationships)
+	})
+	t.Run("wildcard of a container view includes the containers and their neighbours", func(t *testing.T) {
+		elements, relationships := contents(t, "container ss \"key\" {\ninclude *\n}", "key")
+		assert.Equal(t, []string{"API", "DB", "User", "Mail"}, elements)
+		assert.Equal(t, []string{"Uses", "Reads", "Sends"}, relationships)
+	})
+	t.Run("expressions include the selected elements", func(t *testing.T) {
+		elements, relationships := contents(t, "container ss \"key\" {\ninclude \"element.type==Container && element.tag==Public\" ->db\n}", "key")
+		assert.Equal(t, []string{"API", "DB"}, elements)
+		assert.Equal(t, []string{"Reads"}, relationships)
+	})
+	t.Run("relationship expressions include their ends", func(t *testing.T) {
+		elements, relationships := contents(t, "container ss \"key\" {\ninclude \"relationship.tag==Async\"\n}", "key")
+		assert.Equal(t, []string{"API", "DB"}, elements)
+		assert.Equal(t, []string{"Reads"}, relationships)
+	})
+	t.Run("excluded elements and relationships are removed", func(t *testing.T) {
+		elements, relationships := contents(t, "container ss \"key\" {\ninclude *\nexclude mail \"relationship.tag==Async\"\n}", "key")
+		assert.Equal(t, []string{"API", "DB", "User"}, elements)
+		assert.Equal(t, []string{"Uses"}, relationships)
+	})
+	t.Run("steps of dynamic views are included", func(t *testing.T) {
+		elements, relationships := contents(t, "dynamic ss \"key\" {\nuser -> api\n}", "key")
+		assert.Equal(t, []string{"User", "API"}, elements)
+		assert.Equal(t, []string{"Uses"}, relationships)
+	})
+	t.Run("filtered views keep the tagged content of their base view", func(t *testing.T) {
+		elements, relationships := contents(t, "filtered \"containers\" exclude \"Public\" \"key\"\ncontainer ss \"containers\" {\ninclude *\n}", "key")
+		assert.Equal(t, []string{"DB", "User", "Mail"}, elements)
+		assert.Empty(t, relationships)
+	})
+	t.Run("views are found by location", func(t *testing.T) {
+		sut := NewTestAnalyser(model + "views {\nsystemLandscape \"first\" {\ninclude *\n}\nsystemContext ss \"second\" {\ninclude *\n}\n}\n}")
+		ws, _, _ := sut.Analyse()
+		assert.Equal(t, "second", ws.Views().ViewAt(Location{Source: "test.dsl", Line: 17, Pos: 3}).Key)
+		assert.Nil(t, ws.Views().ViewAt(Location{Source: "test.dsl", Line: 1, Pos: 0}))
+	})
+}